@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 下载存档文件名，存放在每个音频库目录下
+// 文件格式与 yt-dlp 的 --download-archive 相同，每行一条“提取器 视频ID”，
+// B站视频ID由BV号和分P组成（如 BV1xx411c7mD_p2），因此重命名或移动文件后仍能识别
+const downloadArchiveFileName = ".lazybala-archive.txt"
+
+// 获取音频库的下载存档路径
+func getDownloadArchivePath(savePath string) string {
+	return filepath.Join("audiobooks", savePath, downloadArchiveFileName)
+}
+
+// 是否启用下载存档
+func isDownloadArchiveEnabled() bool {
+	config, err := loadConfig()
+	if err != nil {
+		return defaultConfig.DownloadArchive
+	}
+	return config.DownloadArchive
+}
+
+// 读取音频库的下载存档，返回视频ID列表
+func loadDownloadArchive(savePath string) ([]string, error) {
+	file, err := os.Open(getDownloadArchivePath(savePath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("读取下载存档失败: %v", err)
+	}
+	defer file.Close()
+
+	ids := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			ids = append(ids, fields[1])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取下载存档失败: %v", err)
+	}
+	return ids, nil
+}
+
+// 清空音频库的下载存档
+func clearDownloadArchive(savePath string) error {
+	if err := os.Remove(getDownloadArchivePath(savePath)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除下载存档失败: %v", err)
+	}
+	return nil
+}
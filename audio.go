@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+)
+
+// 音频区链接，如 https://www.bilibili.com/audio/au123456（单曲）、/audio/am123456（歌单）
+var audioZoneRegex = regexp.MustCompile(`bilibili\.com/audio/(au|am)(\d+)`)
+
+// 解析音频区链接，返回类型（au、am）和ID
+func parseAudioZoneURL(inputURL string) (string, string, bool) {
+	match := audioZoneRegex.FindStringSubmatch(inputURL)
+	if len(match) != 3 {
+		return "", "", false
+	}
+	return match[1], match[2], true
+}
+
+// 音频区的规范链接
+func audioZoneURL(kind, id string) string {
+	return fmt.Sprintf("https://www.bilibili.com/audio/%s%s", kind, id)
+}
+
+// 是否可以使用 ffmpeg 写入元数据和封面
+func hasFFmpeg() bool {
+	_, err := exec.LookPath("ffmpeg")
+	return err == nil
+}
+
+// 音频区下载的额外参数：保存歌词，并把标题、作者、封面写入音频文件
+func audioZoneArgs(url string) []string {
+	kind, _, ok := parseAudioZoneURL(url)
+	if !ok {
+		return nil
+	}
+
+	// 歌词以字幕形式提供，保存为同名的 .lrc 文件
+	args := []string{"--write-subs", "--sub-langs", "all"}
+
+	if !hasFFmpeg() {
+		// 没有 ffmpeg 时无法写入元数据，只保存封面图片
+		fmt.Println("未找到 ffmpeg，音频元数据将不会写入文件")
+		return append(args, "--write-thumbnail")
+	}
+
+	args = append(args, "--embed-metadata", "--embed-thumbnail")
+	if kind == "am" {
+		// 歌单名称作为专辑名
+		args = append(args, "--parse-metadata", "playlist_title:%(album)s")
+	}
+	return args
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+)
+
+// 下载过程中检查限速是否变化的间隔
+const bandwidthCheckInterval = 15 * time.Second
+
+// 限速变化后 yt-dlp 进程被中断，需要以新的限速继续下载
+var errBandwidthChanged = errors.New("限速已调整")
+
+// 计算任务当前应使用的限速（KB/s），0 表示不限速
+// 全局限速按正在下载的任务数平均分配，任务限速未设置时使用配置中的默认值
+func getTaskRateLimit(task *DownloadTask) int {
+	config, err := loadConfig()
+	if err != nil {
+		config = &defaultConfig
+	}
+
+	downloadMutex.RLock()
+	limit := task.BandwidthLimitKBps
+	running := 0
+	for _, t := range downloadTasks {
+		if t.State == TaskStateRunning && t.Type != TaskTypeLive {
+			running++
+		}
+	}
+	downloadMutex.RUnlock()
+
+	if limit <= 0 {
+		limit = config.TaskBandwidthLimitKBps
+	}
+	if config.BandwidthLimitKBps > 0 {
+		share := max(config.BandwidthLimitKBps/max(running, 1), 1)
+		if limit <= 0 || share < limit {
+			limit = share
+		}
+	}
+	return max(limit, 0)
+}
+
+// 设置任务的限速，正在下载的任务会以新的限速继续下载
+func setTaskBandwidthLimit(task *DownloadTask, limitKBps int) {
+	downloadMutex.Lock()
+	task.BandwidthLimitKBps = limitKBps
+	task.Request.BandwidthLimitKBps = limitKBps
+	downloadMutex.Unlock()
+}
+
+// 中断 yt-dlp 进程以便使用新的限速继续下载
+func interruptForBandwidthChange(task *DownloadTask, oldLimit, newLimit int) error {
+	downloadMutex.RLock()
+	cmd := task.Cmd
+	downloadMutex.RUnlock()
+	if cmd == nil || cmd.Process == nil {
+		return nil
+	}
+
+	fmt.Printf("下载任务 %s 限速由 %s 调整为 %s，重新启动下载\n", task.ID, formatRateLimit(oldLimit), formatRateLimit(newLimit))
+	if runtime.GOOS == "windows" {
+		return cmd.Process.Kill()
+	}
+	return cmd.Process.Signal(os.Interrupt)
+}
+
+// 格式化限速显示
+func formatRateLimit(limitKBps int) string {
+	if limitKBps <= 0 {
+		return "不限速"
+	}
+	return fmt.Sprintf("%dKB/s", limitKBps)
+}
+
+// 执行下载，限速变化导致进程中断时使用 --continue 继续下载
+func runDownloadProcess(task *DownloadTask) error {
+	for {
+		err := startDownload(task)
+		if !errors.Is(err, errBandwidthChanged) {
+			return err
+		}
+
+		downloadMutex.Lock()
+		if task.State != TaskStateRunning {
+			downloadMutex.Unlock()
+			return err
+		}
+		resetDownloadingItemsLocked(task)
+		task.ResumePending = true
+		task.Cmd = nil
+		downloadMutex.Unlock()
+	}
+}
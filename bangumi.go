@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// 番剧/影视链接，如 https://www.bilibili.com/bangumi/play/ep123、/play/ss456、/media/md789
+var bangumiRegex = regexp.MustCompile(`bilibili\.com/bangumi/(?:play/(ep|ss)|media/(md))(\d+)`)
+
+// 番剧剧集
+type bangumiEpisode struct {
+	ID        int64  `json:"id"`
+	BVID      string `json:"bvid"`
+	Title     string `json:"title"`      // 集数，如 "1"
+	LongTitle string `json:"long_title"` // 剧集标题
+	Cover     string `json:"cover"`
+	Duration  int64  `json:"duration"` // 毫秒
+	Status    int    `json:"status"`
+	Badge     string `json:"badge"`
+	PubTime   int64  `json:"pub_time"`
+}
+
+// 番剧季度信息
+type bangumiSeason struct {
+	SeasonID    int64            `json:"season_id"`
+	SeasonTitle string           `json:"season_title"`
+	Title       string           `json:"title"`
+	Cover       string           `json:"cover"`
+	Episodes    []bangumiEpisode `json:"episodes"`
+	UpInfo      struct {
+		Uname string `json:"uname"`
+	} `json:"up_info"`
+}
+
+// 剧集状态：13 表示大会员专享
+const bangumiStatusVip = 13
+
+// 是否为大会员专享剧集
+func (ep bangumiEpisode) vipOnly() bool {
+	return ep.Status == bangumiStatusVip || strings.Contains(ep.Badge, "会员")
+}
+
+// 剧集显示名称，如 "第1话 开端"；电影等非数字集数直接使用原标题
+func (ep bangumiEpisode) displayTitle() string {
+	name := ep.Title
+	if isDigits(name) {
+		name = fmt.Sprintf("第%s话", name)
+	}
+	if ep.LongTitle != "" {
+		name += " " + ep.LongTitle
+	}
+	return name
+}
+
+// 判断字符串是否只包含数字
+func isDigits(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
+
+// 解析番剧链接，返回类型（ep、ss、md）和ID
+func parseBangumiURL(inputURL string) (string, string, bool) {
+	match := bangumiRegex.FindStringSubmatch(inputURL)
+	if len(match) != 4 {
+		return "", "", false
+	}
+	kind := match[1]
+	if kind == "" {
+		kind = match[2]
+	}
+	return kind, match[3], true
+}
+
+// 番剧的规范链接
+func bangumiURL(kind, id string) string {
+	if kind == "md" {
+		return fmt.Sprintf("https://www.bilibili.com/bangumi/media/md%s", id)
+	}
+	return fmt.Sprintf("https://www.bilibili.com/bangumi/play/%s%s", kind, id)
+}
+
+// 获取番剧季度信息，md 链接先查询对应的季度
+func getBangumiSeason(kind, id string) (*bangumiSeason, error) {
+	query := ""
+	switch kind {
+	case "ep":
+		query = "ep_id=" + id
+	case "ss":
+		query = "season_id=" + id
+	case "md":
+		var media struct {
+			Media struct {
+				SeasonID int64 `json:"season_id"`
+			} `json:"media"`
+		}
+		if err := getBilibiliAPI("https://api.bilibili.com/pgc/review/user?media_id="+id, &media); err != nil {
+			return nil, err
+		}
+		query = fmt.Sprintf("season_id=%d", media.Media.SeasonID)
+	}
+
+	var season bangumiSeason
+	if err := getBilibiliAPI("https://api.bilibili.com/pgc/view/web/season?"+query, &season); err != nil {
+		return nil, err
+	}
+	return &season, nil
+}
+
+// 当前登录账号是否有有效的大会员
+func hasActiveVip() bool {
+	if !hasCookies() {
+		return false
+	}
+	var nav struct {
+		VipStatus int `json:"vipStatus"`
+	}
+	if err := getBilibiliAPI("https://api.bilibili.com/x/web-interface/nav", &nav); err != nil {
+		return false
+	}
+	return nav.VipStatus == 1
+}
+
+// 大会员专享剧集的错误信息，如 "This video is for premium members only"
+func isVipOnlyError(msg string) bool {
+	return strings.Contains(msg, "premium members only") || strings.Contains(msg, "大会员")
+}
+
+// 单集链接的剧集需要大会员而当前账号没有时返回错误
+func checkBangumiAccess(url string) error {
+	kind, id, ok := parseBangumiURL(url)
+	if !ok || kind != "ep" {
+		return nil
+	}
+
+	season, err := getBangumiSeason(kind, id)
+	if err != nil {
+		// 查询失败时交给 yt-dlp 处理
+		fmt.Printf("获取番剧信息失败: %v\n", err)
+		return nil
+	}
+	for _, ep := range season.Episodes {
+		if fmt.Sprint(ep.ID) == id && ep.vipOnly() && !hasActiveVip() {
+			return fmt.Errorf("《%s》%s 为大会员专享，当前登录账号没有大会员权限", season.Title, ep.displayTitle())
+		}
+	}
+	return nil
+}
+
+// 获取番剧预检查信息：季度标题、剧集编号以及大会员专享标记
+// 单集链接的剧集不在正片列表中（如花絮、预告）时返回 nil，按普通视频处理
+func getBangumiInfo(url, kind, id, quality string, selection ItemSelection) (*PreCheckResponse, error) {
+	season, err := getBangumiSeason(kind, id)
+	if err != nil {
+		return nil, fmt.Errorf("获取番剧信息失败: %v", err)
+	}
+	vip := hasActiveVip()
+
+	title := season.Title
+	if season.SeasonTitle != "" && !strings.Contains(title, season.SeasonTitle) {
+		title += " " + season.SeasonTitle
+	}
+
+	entries := make([]VideoInfo, 0, len(season.Episodes))
+	for i, ep := range season.Episodes {
+		entry := videoInfoFromAPI(i+1, ep.BVID, ep.displayTitle(), ep.Cover, int(ep.Duration/1000), ep.PubTime)
+		entry.URL = bangumiURL("ep", fmt.Sprint(ep.ID))
+		entry.Episode = ep.Title
+		entry.VipOnly = ep.vipOnly()
+		entries = append(entries, entry)
+	}
+
+	// 单集链接只返回该剧集
+	if kind == "ep" {
+		index := slices.IndexFunc(entries, func(entry VideoInfo) bool {
+			return entry.URL == bangumiURL("ep", id)
+		})
+		if index == -1 {
+			return nil, nil
+		}
+		entries = entries[index : index+1]
+	}
+
+	var selected []VideoInfo
+	for _, entry := range entries {
+		if kind == "ep" || selection.IsEmpty() || selection.Contains(entry.Index, entry.ID) {
+			selected = append(selected, entry)
+		}
+	}
+
+	// 按画质估算文件大小，保留接口返回的剧集标题
+	titles := make([]string, len(selected))
+	for i, entry := range selected {
+		titles[i] = entry.Title
+	}
+	fillEntryDetails(url, selected, quality, kind != "ep")
+	for i := range selected {
+		selected[i].Title = titles[i]
+	}
+
+	response := &PreCheckResponse{
+		Title:      title,
+		Uploader:   season.UpInfo.Uname,
+		Duration:   fmt.Sprintf("共%d集", len(season.Episodes)),
+		Thumbnail:  season.Cover,
+		AudioCount: len(entries),
+		IsPlaylist: kind != "ep",
+		Entries:    entries,
+	}
+	if kind == "ep" && len(selected) == 1 {
+		response.Title = fmt.Sprintf("%s %s", title, selected[0].Title)
+		response.Duration = selected[0].Duration
+		response.Entries = nil
+	}
+	applyEstimate(response, selected)
+
+	for _, entry := range selected {
+		if entry.VipOnly {
+			response.VipOnlyCount++
+		}
+	}
+	if response.VipOnlyCount > 0 && !vip {
+		response.VipWarning = fmt.Sprintf("%d 集为大会员专享，当前登录账号没有大会员权限，这些剧集将无法下载", response.VipOnlyCount)
+	}
+
+	return response, nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -11,6 +12,8 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -42,7 +45,8 @@ type QRData struct {
 
 // 生成哔哩哔哩登录二维码
 func generateBilibiliQRCode() (*QRData, error) {
-	resp, err := http.Get("https://passport.bilibili.com/x/passport-login/web/qrcode/generate")
+	client := newHTTPClient(ProxyPurposeBilibili, 10*time.Second)
+	resp, err := client.Get("https://passport.bilibili.com/x/passport-login/web/qrcode/generate")
 	if err != nil {
 		return nil, fmt.Errorf("请求二维码生成接口失败: %v", err)
 	}
@@ -78,7 +82,8 @@ func checkLoginStatus(qrcodeKey string) (int, string, error) {
 
 	fmt.Printf("检查登录状态: qrcode_key=%s\n", qrcodeKey)
 
-	resp, err := http.Get(apiURL)
+	client := newHTTPClient(ProxyPurposeBilibili, 10*time.Second)
+	resp, err := client.Get(apiURL)
 	if err != nil {
 		return -1, "", fmt.Errorf("请求登录状态接口失败: %v", err)
 	}
@@ -180,28 +185,171 @@ func saveCookies(cookies string) error {
 	return os.WriteFile(cookiesFile, []byte(cookies), 0644)
 }
 
+// 短链接，如 https://b23.tv/xxxx，移动端分享时常带有标题等其他文字
+var shortLinkRegex = regexp.MustCompile(`(?:https?://)?(?:b23\.tv|bili2233\.cn)/[A-Za-z0-9]+`)
+
+// 跟随短链接的跳转，返回最终的链接
+func resolveShortLink(shortURL string) (string, error) {
+	if !strings.HasPrefix(shortURL, "http") {
+		shortURL = "https://" + shortURL
+	}
+
+	client := newHTTPClient(ProxyPurposeBilibili, 10*time.Second)
+	// 只需要跳转地址，不跟随跳转
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	current := shortURL
+	for i := 0; i < 5; i++ {
+		req, err := http.NewRequest("GET", current, nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("请求短链接失败: %v", err)
+		}
+		resp.Body.Close()
+
+		location := resp.Header.Get("Location")
+		if location == "" {
+			return "", fmt.Errorf("短链接没有跳转地址 (HTTP %d)", resp.StatusCode)
+		}
+		next, err := resp.Request.URL.Parse(location)
+		if err != nil {
+			return "", fmt.Errorf("无效的跳转地址: %s", location)
+		}
+		current = next.String()
+
+		// 跳转到非短链接域名即为最终地址
+		if !shortLinkRegex.MatchString(current) {
+			return current, nil
+		}
+	}
+	return "", fmt.Errorf("短链接跳转次数过多")
+}
+
+// av 号，如 av170001 或 https://www.bilibili.com/video/av170001
+var avRegex = regexp.MustCompile(`(?i)(?:^|/video/)av(\d+)(?:[/?#]|$)`)
+
+// 分P参数，如 ?p=2
+var partRegex = regexp.MustCompile(`[?&]p=(\d+)`)
+
+// av 号与 BV 号转换参数
+const (
+	avXorCode  = 23442827791579
+	maxAid     = 1 << 51
+	bvAlphabet = "FcwAPNKTMug3GV5Lj7EJnHpWsx4tb8haYeviqBz6rkCy12mUSDQX9RdoZf"
+)
+
+// 将 av 号转换为 BV 号
+func avToBV(aid uint64) string {
+	bv := []byte("BV1000000000")
+	tmp := (maxAid | aid) ^ avXorCode
+	for i := len(bv) - 1; tmp > 0; i-- {
+		bv[i] = bvAlphabet[tmp%uint64(len(bvAlphabet))]
+		tmp /= uint64(len(bvAlphabet))
+	}
+	bv[3], bv[9] = bv[9], bv[3]
+	bv[4], bv[7] = bv[7], bv[4]
+	return string(bv)
+}
+
+// 去掉链接中的分P参数，使条目选择按分P序号作用于多P视频的全部分P
+func stripPartSelector(videoURL string) string {
+	return partRegex.ReplaceAllString(videoURL, "")
+}
+
 // 解析哔哩哔哩链接
 func parseURL(inputURL string) (string, error) {
+	// b23.tv 短链接先解析出实际地址（BV号形式的短链接可以直接识别）
+	if shortURL := shortLinkRegex.FindString(inputURL); shortURL != "" && !strings.Contains(shortURL, "/BV") {
+		resolved, err := resolveShortLink(shortURL)
+		if err != nil {
+			return "", fmt.Errorf("解析短链接失败: %v", err)
+		}
+		fmt.Printf("短链接 %s 解析为: %s\n", shortURL, resolved)
+		return parseURL(resolved)
+	}
+
+	// 番剧和影视，匹配格式: https://www.bilibili.com/bangumi/play/ep{id}、/play/ss{id}、/media/md{id}
+	if kind, id, ok := parseBangumiURL(inputURL); ok {
+		return bangumiURL(kind, id), nil
+	}
+
+	// 音频区单曲和歌单，匹配格式: https://www.bilibili.com/audio/au{id}、/audio/am{id}
+	if kind, id, ok := parseAudioZoneURL(inputURL); ok {
+		return audioZoneURL(kind, id), nil
+	}
+
+	// 直播间，匹配格式: https://live.bilibili.com/{roomid}、https://live.bilibili.com/h5/{roomid}
+	if roomID, ok := parseLiveRoomURL(inputURL); ok {
+		return liveRoomURL(roomID), nil
+	}
+
+	// 收藏夹和稍后再看链接中可能带有当前播放视频的BV号，需要先识别
+	// 匹配格式: https://space.bilibili.com/{uid}/favlist?fid={id}、https://www.bilibili.com/medialist/detail/ml{id}、https://www.bilibili.com/list/ml{id}
+	favRegex := regexp.MustCompile(`(?:space\.bilibili\.com/\d+/favlist\?(?:.*&)?fid=|bilibili\.com/(?:medialist/detail|list)/ml)(\d+)`)
+	if favMatch := favRegex.FindStringSubmatch(inputURL); len(favMatch) == 2 {
+		id, _ := strconv.ParseInt(favMatch[1], 10, 64)
+		return favoriteFolderURL(id), nil
+	}
+	if regexp.MustCompile(`bilibili\.com/(?:watchlater|list/watchlater)`).MatchString(inputURL) {
+		return watchLaterURL, nil
+	}
+
 	// 正则表达式匹配 BV 号
 	bvRegex := regexp.MustCompile(`BV[a-zA-Z0-9]+`)
 	bvMatch := bvRegex.FindString(inputURL)
 
 	if bvMatch != "" {
-		// 普通视频链接
-		return fmt.Sprintf("https://www.bilibili.com/video/%s/", bvMatch), nil
+		return videoPageURL(bvMatch, inputURL), nil
 	}
 
-	// 检查是否为合集链接
-	// 匹配格式: https://space.bilibili.com/{uid}/lists/{sid}?type=season
+	// 检查是否为合集或系列链接
+	// 匹配格式: https://space.bilibili.com/{uid}/lists/{sid}?type=season 或 ?type=series
 	spaceRegex := regexp.MustCompile(`space\.bilibili\.com/(\d+)/lists/(\d+)`)
 	spaceMatch := spaceRegex.FindStringSubmatch(inputURL)
 
 	if len(spaceMatch) == 3 {
 		uid := spaceMatch[1]
 		sid := spaceMatch[2]
+		if strings.Contains(inputURL, "type=series") {
+			return fmt.Sprintf("https://space.bilibili.com/%s/channel/seriesdetail?sid=%s", uid, sid), nil
+		}
 		return fmt.Sprintf("https://space.bilibili.com/%s/lists/%s?type=season", uid, sid), nil
 	}
 
+	// 旧版空间页面的合集和系列链接
+	// 匹配格式: https://space.bilibili.com/{uid}/channel/collectiondetail?sid={sid}
+	channelRegex := regexp.MustCompile(`space\.bilibili\.com/(\d+)/channel/(collectiondetail|seriesdetail)\?sid=(\d+)`)
+	if channelMatch := channelRegex.FindStringSubmatch(inputURL); len(channelMatch) == 4 {
+		uid, kind, sid := channelMatch[1], channelMatch[2], channelMatch[3]
+		if kind == "seriesdetail" {
+			return fmt.Sprintf("https://space.bilibili.com/%s/channel/seriesdetail?sid=%s", uid, sid), nil
+		}
+		return fmt.Sprintf("https://space.bilibili.com/%s/lists/%s?type=season", uid, sid), nil
+	}
+
+	// UP主全部投稿
+	// 匹配格式: https://space.bilibili.com/{uid}/video 或 https://space.bilibili.com/{uid}/upload/video
+	uploadsRegex := regexp.MustCompile(`space\.bilibili\.com/(\d+)/(?:upload/)?video`)
+	if uploadsMatch := uploadsRegex.FindStringSubmatch(inputURL); len(uploadsMatch) == 2 {
+		return fmt.Sprintf("https://space.bilibili.com/%s/video", uploadsMatch[1]), nil
+	}
+
+	// 旧版 av 号转换为 BV 号，只识别视频链接或单独输入的 av 号
+	if avMatch := avRegex.FindStringSubmatch(inputURL); len(avMatch) == 2 {
+		aid, err := strconv.ParseUint(avMatch[1], 10, 64)
+		if err != nil || aid == 0 || aid >= maxAid {
+			return "", fmt.Errorf("无效的av号: av%s", avMatch[1])
+		}
+		return videoPageURL(avToBV(aid), inputURL), nil
+	}
+
 	// 如果都不匹配，返回原链接
 	if strings.Contains(inputURL, "bilibili.com") {
 		return inputURL, nil
@@ -210,6 +358,14 @@ func parseURL(inputURL string) (string, error) {
 	return "", fmt.Errorf("无法识别的哔哩哔哩链接格式")
 }
 
+// 生成普通视频链接，保留分P选择；未指定分P时多P视频下载全部分P
+func videoPageURL(bvid, inputURL string) string {
+	if partMatch := partRegex.FindStringSubmatch(inputURL); len(partMatch) == 2 {
+		return fmt.Sprintf("https://www.bilibili.com/video/%s/?p=%s", bvid, partMatch[1])
+	}
+	return fmt.Sprintf("https://www.bilibili.com/video/%s/", bvid)
+}
+
 // Base64 编码
 func encodeBase64(data []byte) string {
 	return base64.StdEncoding.EncodeToString(data)
@@ -254,9 +410,7 @@ func checkCookiesValid() (bool, error) {
 // 通过API验证cookies有效性
 func validateCookiesWithAPI() (bool, error) {
 	// 使用cookies访问B站用户信息API
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+	client := newHTTPClient(ProxyPurposeBilibili, 10*time.Second)
 
 	req, err := http.NewRequest("GET", "https://api.bilibili.com/x/web-interface/nav", nil)
 	if err != nil {
@@ -345,26 +499,46 @@ func readCookies() (string, error) {
 	return string(data), nil
 }
 
-// 获取视频信息
-func getVideoInfo(url string) (*PreCheckResponse, error) {
+// 补全条目详细信息时最多处理的条目数，避免超长合集预检查耗时过久
+const precheckDetailLimit = 300
+
+// 预检查时逐条输出的条目详细信息，filesize 等字段对应按画质选中的格式
+const ytDlpDetailTemplate = "%(.{playlist_index,id,title,duration,thumbnail,upload_date,filesize,filesize_approx,tbr})j"
+
+// 获取视频信息，并按画质、条目选择和筛选条件估算下载大小
+func getVideoInfo(url string, quality string, selection ItemSelection, filter CatalogFilter) (*PreCheckResponse, error) {
 	// 确保 yt-dlp 有执行权限
 	if err := ensureYtDlpExecutable(); err != nil {
 		return nil, fmt.Errorf("yt-dlp 权限检查失败: %v", err)
 	}
 
+	// 直播间通过接口获取开播状态
+	if roomID, ok := parseLiveRoomURL(url); ok {
+		return getLiveRoomPreCheck(roomID)
+	}
+
+	// 番剧通过接口获取季度和剧集信息
+	if kind, id, ok := parseBangumiURL(url); ok {
+		if response, err := getBangumiInfo(url, kind, id, quality, selection); err != nil || response != nil {
+			return response, err
+		}
+	}
+
 	ytDlpPath := getYtDlpPath()
 
-	// 首先获取播放列表基本信息
+	// 先以平铺方式获取播放列表及全部条目
 	args := []string{
-		"--dump-json",
+		"--dump-single-json",
 		"--no-download",
 		"--flat-playlist",
+		"--no-warnings",
 	}
 
 	// 添加 cookies 如果存在
 	if hasCookies() {
 		args = append(args, "--cookies", getCookiesPath())
 	}
+	args = append(args, ytDlpProxyArgs()...)
 
 	args = append(args, url)
 
@@ -374,114 +548,249 @@ func getVideoInfo(url string) (*PreCheckResponse, error) {
 		return nil, fmt.Errorf("执行yt-dlp失败: %v", err)
 	}
 
-	// 解析JSON输出
-	lines := strings.Split(string(output), "\n")
-	var entries []VideoInfo
-	var mainInfo map[string]interface{}
-	var playlistTitle string
-	var playlistUploader string
-	var totalCount int
+	var info map[string]interface{}
+	if err := json.Unmarshal(output, &info); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
 
-	for _, line := range lines {
-		if strings.TrimSpace(line) == "" {
-			continue
+	rawEntries, isPlaylist := info["entries"].([]interface{})
+	if !isPlaylist {
+		// 单个视频，平铺模式下返回的就是完整信息
+		entries := []VideoInfo{videoInfoFromEntry(info, 1)}
+		fillEntryDetails(url, entries, quality, false)
+
+		response := &PreCheckResponse{
+			Title:      getString(info, "title"),
+			Uploader:   getString(info, "uploader"),
+			Duration:   entries[0].Duration,
+			Thumbnail:  entries[0].Thumbnail,
+			AudioCount: 1,
+			IsPlaylist: false,
+			Entries:    nil,
 		}
+		applyEstimate(response, entries)
+		return response, nil
+	}
 
-		var info map[string]interface{}
-		if err := json.Unmarshal([]byte(line), &info); err != nil {
+	entries := make([]VideoInfo, 0, len(rawEntries))
+	var candidates []VideoInfo
+	for i, raw := range rawEntries {
+		entry, ok := raw.(map[string]interface{})
+		if !ok {
 			continue
 		}
-
-		if mainInfo == nil {
-			mainInfo = info
-			playlistTitle = getString(info, "playlist")
-			if playlistTitle == "" {
-				playlistTitle = getString(info, "playlist_title")
-			}
-			playlistUploader = getString(info, "playlist_uploader")
-			totalCount = int(getFloat64(info, "n_entries"))
-		}
-
-		// 对于播放列表，我们只显示前几个条目的基本信息
-		if len(entries) < 5 {
-			entries = append(entries, VideoInfo{
-				Title:     fmt.Sprintf("第%d集", len(entries)+1),
-				Duration:  "未知",
-				Thumbnail: "",
-			})
+		video := videoInfoFromEntry(entry, i+1)
+		entries = append(entries, video)
+		if (selection.IsEmpty() || selection.Contains(video.Index, video.ID)) && filter.MatchesTitle(getString(entry, "title")) {
+			candidates = append(candidates, video)
 		}
 	}
 
-	if mainInfo == nil {
-		return nil, fmt.Errorf("无法获取视频信息")
+	// 平铺模式下没有格式信息，部分提取器也不返回时长、封面和上传日期，对选中的条目逐条补全
+	fillEntryDetails(url, candidates, quality, true)
+	selected := make([]VideoInfo, 0, len(candidates))
+	for _, detail := range candidates {
+		entries[slices.IndexFunc(entries, func(entry VideoInfo) bool {
+			return entry.Index == detail.Index
+		})] = detail
+
+		// 补全后按实际标题和上传日期再筛选一次
+		if filter.MatchesTitle(detail.RealTitle()) && filter.MatchesDate(detail.UploadDate) {
+			selected = append(selected, detail)
+		}
 	}
 
-	// 如果是单个视频，获取详细信息
-	if totalCount <= 1 {
-		return getSingleVideoInfo(url)
+	playlistUploader := getString(info, "uploader")
+	if playlistUploader == "" {
+		playlistUploader = getString(info, "playlist_uploader")
 	}
 
-	// 构建播放列表响应
 	response := &PreCheckResponse{
-		Title:      playlistTitle,
+		Title:      getString(info, "title"),
 		Uploader:   playlistUploader,
-		Duration:   fmt.Sprintf("共%d集", totalCount),
-		Thumbnail:  "",
-		AudioCount: totalCount,
-		IsPlaylist: totalCount > 1,
+		Duration:   fmt.Sprintf("共%d集", len(entries)),
+		Thumbnail:  getThumbnail(info),
+		AudioCount: len(entries),
+		IsPlaylist: len(entries) > 1,
 		Entries:    entries,
 	}
 
+	if response.Thumbnail == "" && len(entries) > 0 {
+		response.Thumbnail = entries[0].Thumbnail
+	}
 	if response.AudioCount == 0 {
 		response.AudioCount = 1
 	}
+	applyEstimate(response, selected)
 
 	return response, nil
 }
 
-// 获取单个视频详细信息
-func getSingleVideoInfo(url string) (*PreCheckResponse, error) {
-	// 确保 yt-dlp 有执行权限
-	if err := ensureYtDlpExecutable(); err != nil {
-		return nil, fmt.Errorf("yt-dlp 权限检查失败: %v", err)
+// 条目的实际标题，没有标题时返回空字符串
+func (v VideoInfo) RealTitle() string {
+	if v.untitled {
+		return ""
 	}
+	return v.Title
+}
 
-	ytDlpPath := getYtDlpPath()
+// 将 yt-dlp 的条目信息转换为 VideoInfo
+func videoInfoFromEntry(entry map[string]interface{}, index int) VideoInfo {
+	if playlistIndex := int(getFloat64(entry, "playlist_index")); playlistIndex > 0 {
+		index = playlistIndex
+	}
 
-	args := []string{
-		"--dump-json",
-		"--no-download",
+	// 没有标题时显示为“第N集”
+	title := getString(entry, "title")
+	untitled := title == ""
+	if untitled {
+		title = fmt.Sprintf("第%d集", index)
 	}
 
-	// 添加 cookies 如果存在
+	videoURL := getString(entry, "webpage_url")
+	if videoURL == "" {
+		videoURL = getString(entry, "url")
+	}
+
+	// 没有文件大小时按平均码率（kbit/s）和时长估算
+	duration := getFloat64(entry, "duration")
+	filesize := int64(getFloat64(entry, "filesize"))
+	if filesize <= 0 {
+		filesize = int64(getFloat64(entry, "filesize_approx"))
+	}
+	if filesize <= 0 {
+		filesize = int64(getFloat64(entry, "tbr") * 1000 / 8 * duration)
+	}
+
+	return VideoInfo{
+		Index:           index,
+		ID:              getString(entry, "id"),
+		URL:             videoURL,
+		Title:           title,
+		Duration:        formatDuration(duration),
+		DurationSeconds: duration,
+		Thumbnail:       getThumbnail(entry),
+		Filesize:        filesize,
+		UploadDate:      getString(entry, "upload_date"),
+		untitled:        untitled,
+	}
+}
+
+// 获取封面地址，没有 thumbnail 字段时取 thumbnails 列表的最后一项（通常分辨率最高）
+func getThumbnail(m map[string]interface{}) string {
+	if thumbnail := getString(m, "thumbnail"); thumbnail != "" {
+		return thumbnail
+	}
+	thumbnails, _ := m["thumbnails"].([]interface{})
+	for i := len(thumbnails) - 1; i >= 0; i-- {
+		if thumb, ok := thumbnails[i].(map[string]interface{}); ok {
+			if thumbURL := getString(thumb, "url"); thumbURL != "" {
+				return thumbURL
+			}
+		}
+	}
+	return ""
+}
+
+// 按画质完整解析条目，补全时长、封面和所选格式的文件大小
+func fillEntryDetails(url string, entries []VideoInfo, quality string, isPlaylist bool) {
+	entries = entries[:min(len(entries), precheckDetailLimit)]
+	if len(entries) == 0 {
+		return
+	}
+
+	args := []string{
+		"-f", quality,
+		"--print", ytDlpDetailTemplate,
+		"--ignore-errors",
+		"--no-warnings",
+	}
+	if isPlaylist {
+		indices := make([]int, 0, len(entries))
+		for _, entry := range entries {
+			indices = append(indices, entry.Index)
+		}
+		args = append(args, "--playlist-items", formatItemIndices(indices))
+	}
 	if hasCookies() {
 		args = append(args, "--cookies", getCookiesPath())
 	}
-
+	args = append(args, ytDlpProxyArgs()...)
 	args = append(args, url)
 
-	cmd := exec.Command(ytDlpPath, args...)
-	output, err := cmd.Output()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+
+	// 部分条目解析失败时 yt-dlp 返回非零退出码，仍使用已输出的信息
+	output, err := exec.CommandContext(ctx, getYtDlpPath(), args...).Output()
 	if err != nil {
-		return nil, fmt.Errorf("执行yt-dlp失败: %v", err)
+		fmt.Printf("补全条目信息时出错: %v\n", err)
 	}
 
-	var info map[string]interface{}
-	if err := json.Unmarshal(output, &info); err != nil {
-		return nil, fmt.Errorf("解析响应失败: %v", err)
+	for _, line := range strings.Split(string(output), "\n") {
+		var info map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &info); err != nil {
+			continue
+		}
+
+		// 单个视频没有播放列表序号
+		pos := 0
+		if isPlaylist {
+			pos = slices.IndexFunc(entries, func(entry VideoInfo) bool {
+				return entry.Index == int(getFloat64(info, "playlist_index"))
+			})
+			if pos == -1 {
+				continue
+			}
+		}
+
+		entry := &entries[pos]
+		detail := videoInfoFromEntry(info, entry.Index)
+		if entry.ID == "" {
+			entry.ID = detail.ID
+		}
+		if !detail.untitled {
+			entry.Title = detail.Title
+			entry.untitled = false
+		}
+		if detail.DurationSeconds > 0 {
+			entry.Duration = detail.Duration
+			entry.DurationSeconds = detail.DurationSeconds
+		}
+		if detail.Thumbnail != "" {
+			entry.Thumbnail = detail.Thumbnail
+		}
+		if detail.Filesize > 0 {
+			entry.Filesize = detail.Filesize
+		}
+		if detail.UploadDate != "" {
+			entry.UploadDate = detail.UploadDate
+		}
 	}
+}
 
-	response := &PreCheckResponse{
-		Title:      getString(info, "title"),
-		Uploader:   getString(info, "uploader"),
-		Duration:   formatDuration(getFloat64(info, "duration")),
-		Thumbnail:  getString(info, "thumbnail"),
-		AudioCount: 1,
-		IsPlaylist: false,
-		Entries:    nil,
+// 汇总选中条目的总时长和预估大小，未知大小的条目按已知条目的平均大小估算
+func applyEstimate(response *PreCheckResponse, selected []VideoInfo) {
+	var known int64
+	knownCount := 0
+	for _, entry := range selected {
+		response.TotalDurationSeconds += entry.DurationSeconds
+		if entry.Filesize > 0 {
+			known += entry.Filesize
+			knownCount++
+		}
 	}
 
-	return response, nil
+	response.SelectedCount = len(selected)
+	response.TotalDuration = formatDuration(response.TotalDurationSeconds)
+	response.UnknownSizeCount = len(selected) - knownCount
+	response.EstimatedSize = known
+	if knownCount > 0 {
+		response.EstimatedSize += known / int64(knownCount) * int64(response.UnknownSizeCount)
+	}
+	if response.EstimatedSize > 0 {
+		response.EstimatedSizeText = formatFileSize(response.EstimatedSize)
+	}
 }
 
 // 辅助函数：从map中安全获取字符串
@@ -529,9 +838,7 @@ type BilibiliUserInfo struct {
 
 // 获取哔哩哔哩用户信息
 func getBilibiliUserInfo() (*BilibiliUserInfo, error) {
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+	client := newHTTPClient(ProxyPurposeBilibili, 10*time.Second)
 
 	// 获取用户基本信息
 	req, err := http.NewRequest("GET", "https://api.bilibili.com/x/web-interface/nav", nil)
@@ -597,9 +904,7 @@ func getBilibiliUserInfo() (*BilibiliUserInfo, error) {
 
 // 获取用户统计信息（关注数、粉丝数）
 func getUserStats(mid int64) (int, int) {
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-	}
+	client := newHTTPClient(ProxyPurposeBilibili, 5*time.Second)
 
 	url := fmt.Sprintf("https://api.bilibili.com/x/relation/stat?vmid=%d", mid)
 	req, err := http.NewRequest("GET", url, nil)
@@ -0,0 +1,75 @@
+package main
+
+import "testing"
+
+func TestAvToBV(t *testing.T) {
+	tests := []struct {
+		aid  uint64
+		want string
+	}{
+		{2, "BV1xx411c7mD"},
+		{170001, "BV17x411w7KC"},
+	}
+	for _, tt := range tests {
+		if got := avToBV(tt.aid); got != tt.want {
+			t.Errorf("avToBV(%d) = %s, want %s", tt.aid, got, tt.want)
+		}
+	}
+}
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"https://www.bilibili.com/video/BV17x411w7KC", "https://www.bilibili.com/video/BV17x411w7KC/"},
+		{"https://www.bilibili.com/video/BV17x411w7KC/?p=3", "https://www.bilibili.com/video/BV17x411w7KC/?p=3"},
+		{"av170001", "https://www.bilibili.com/video/BV17x411w7KC/"},
+		{"AV170001", "https://www.bilibili.com/video/BV17x411w7KC/"},
+		{"https://www.bilibili.com/video/av170001/?p=2", "https://www.bilibili.com/video/BV17x411w7KC/?p=2"},
+		{"https://space.bilibili.com/123/video?keyword=av5", "https://space.bilibili.com/123/video"},
+		{"https://space.bilibili.com/123/upload/video", "https://space.bilibili.com/123/video"},
+		{"https://space.bilibili.com/123/lists/456?type=season", "https://space.bilibili.com/123/lists/456?type=season"},
+		{"https://space.bilibili.com/123/lists/456?type=series", "https://space.bilibili.com/123/channel/seriesdetail?sid=456"},
+		{"https://space.bilibili.com/123/channel/collectiondetail?sid=456", "https://space.bilibili.com/123/lists/456?type=season"},
+		{"https://www.bilibili.com/read/av5", "https://www.bilibili.com/read/av5"},
+	}
+	for _, tt := range tests {
+		got, err := parseURL(tt.input)
+		if err != nil {
+			t.Errorf("parseURL(%q) error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseURL(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseURLInvalid(t *testing.T) {
+	for _, input := range []string{"av0", "https://example.com/av5", "hello"} {
+		if got, err := parseURL(input); err == nil {
+			t.Errorf("parseURL(%q) = %s, want error", input, got)
+		}
+	}
+}
+
+func TestVideoInfoFromEntryTitle(t *testing.T) {
+	tests := []struct {
+		entry     map[string]interface{}
+		index     int
+		title     string
+		realTitle string
+	}{
+		{map[string]interface{}{"id": "BV1aa", "title": "序章"}, 1, "序章", "序章"},
+		{map[string]interface{}{"id": "BV1aa", "title": "第3集"}, 3, "第3集", "第3集"},
+		{map[string]interface{}{"id": "BV1aa"}, 3, "第3集", ""},
+		{map[string]interface{}{"id": "BV1aa", "title": "", "playlist_index": float64(5)}, 1, "第5集", ""},
+	}
+	for _, tt := range tests {
+		video := videoInfoFromEntry(tt.entry, tt.index)
+		if video.Title != tt.title || video.RealTitle() != tt.realTitle {
+			t.Errorf("videoInfoFromEntry(%v, %d) title = %q, real title = %q, want %q, %q", tt.entry, tt.index, video.Title, video.RealTitle(), tt.title, tt.realTitle)
+		}
+	}
+}
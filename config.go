@@ -4,13 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // 默认配置
@@ -20,6 +20,14 @@ var defaultConfig = Config{
 	Quality:        "bestaudio/best",
 	RetryCount:     5,
 	WriteThumbnail: true,
+
+	MaxConcurrentDownloads: defaultMaxConcurrentDownloads,
+	HistoryMaxEntries:      defaultHistoryMaxEntries,
+	StallTimeoutSeconds:    defaultStallTimeoutSeconds,
+	ItemRetryAttempts:      3,
+	ItemRetryDelaySeconds:  30,
+	DownloadArchive:        true,
+	MinFreeSpaceMB:         1024,
 }
 
 // 加载配置
@@ -28,7 +36,8 @@ func loadConfig() (*Config, error) {
 
 	// 如果配置文件不存在，返回默认配置
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return &defaultConfig, nil
+		config := defaultConfig
+		return &config, nil
 	}
 
 	data, err := os.ReadFile(configPath)
@@ -36,7 +45,8 @@ func loadConfig() (*Config, error) {
 		return nil, fmt.Errorf("读取配置文件失败: %v", err)
 	}
 
-	var config Config
+	// 以默认配置为基础，配置文件中缺少的字段使用默认值
+	config := defaultConfig
 	if err := json.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("解析配置文件失败: %v", err)
 	}
@@ -65,6 +75,28 @@ func saveConfigToFile(config Config) error {
 	return generateYtDlpConfig(config)
 }
 
+// 将数据以 JSON 格式写入文件
+// 先写入临时文件再替换，避免写入中断导致文件损坏
+func writeJSONFile(path string, v any) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %v", err)
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化 %s 失败: %v", filepath.Base(path), err)
+	}
+
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("写入 %s 失败: %v", filepath.Base(path), err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return fmt.Errorf("替换 %s 失败: %v", filepath.Base(path), err)
+	}
+	return nil
+}
+
 // 生成 yt-dlp 配置文件
 func generateYtDlpConfig(config Config) error {
 	configDir := "config"
@@ -118,7 +150,8 @@ type GitHubRelease struct {
 
 // 检查LazyBala应用最新版本
 func checkLatestAppVersion() (string, string, error) {
-	resp, err := http.Get("https://api.github.com/repos/kis2show/lazybala/releases/latest")
+	client := newHTTPClient(ProxyPurposeGitHub, 30*time.Second)
+	resp, err := client.Get("https://api.github.com/repos/kis2show/lazybala/releases/latest")
 	if err != nil {
 		return "", "", fmt.Errorf("获取LazyBala版本信息失败: %v", err)
 	}
@@ -145,7 +178,8 @@ func checkLatestAppVersion() (string, string, error) {
 
 // 检查yt-dlp最新版本
 func checkLatestYtDlpVersion() (string, string, error) {
-	resp, err := http.Get("https://api.github.com/repos/yt-dlp/yt-dlp/releases/latest")
+	client := newHTTPClient(ProxyPurposeGitHub, 30*time.Second)
+	resp, err := client.Get("https://api.github.com/repos/yt-dlp/yt-dlp/releases/latest")
 	if err != nil {
 		return "", "", fmt.Errorf("获取yt-dlp版本信息失败: %v", err)
 	}
@@ -300,7 +334,7 @@ func updateYtDlp() error {
 	}
 
 	// 下载新版本
-	resp, err := http.Get(downloadURL)
+	resp, err := newHTTPClient(ProxyPurposeGitHub, 0).Get(downloadURL)
 	if err != nil {
 		return fmt.Errorf("下载失败: %v", err)
 	}
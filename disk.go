@@ -0,0 +1,205 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 预检查得到的预估大小保留时间
+const sizeEstimateTTL = 30 * time.Minute
+
+// 预估大小缓存
+type sizeEstimate struct {
+	Size      int64
+	CreatedAt time.Time
+}
+
+var (
+	sizeEstimateMutex sync.Mutex
+	sizeEstimates     = make(map[string]sizeEstimate)
+)
+
+// 预估大小缓存键：链接、画质、条目选择和筛选条件都会影响下载大小
+func sizeEstimateKey(url, quality string, selection ItemSelection, filter CatalogFilter) string {
+	return fmt.Sprintf("%s\n%s\n%v\n%v", url, quality, selection, filter)
+}
+
+// 记录预检查得到的预估大小
+func saveSizeEstimate(key string, size int64) {
+	sizeEstimateMutex.Lock()
+	defer sizeEstimateMutex.Unlock()
+
+	// 顺便清理过期记录
+	for key, estimate := range sizeEstimates {
+		if time.Since(estimate.CreatedAt) > sizeEstimateTTL {
+			delete(sizeEstimates, key)
+		}
+	}
+	if size > 0 {
+		sizeEstimates[key] = sizeEstimate{Size: size, CreatedAt: time.Now()}
+	}
+}
+
+// 获取预估大小，没有预检查记录时返回 0
+func getSizeEstimate(key string) int64 {
+	sizeEstimateMutex.Lock()
+	defer sizeEstimateMutex.Unlock()
+
+	estimate, ok := sizeEstimates[key]
+	if !ok || time.Since(estimate.CreatedAt) > sizeEstimateTTL {
+		return 0
+	}
+	return estimate.Size
+}
+
+// 获取目录所在磁盘的剩余空间，目录不存在时使用最近的已存在的上级目录
+func getFreeSpace(dir string) (uint64, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return 0, fmt.Errorf("目录不存在: %s", dir)
+		}
+		dir = parent
+	}
+	return diskFreeSpace(dir)
+}
+
+// 获取任务的预估大小，优先使用预检查的结果；没有匹配的预检查记录且 compute 为 true 时重新估算
+// 无法估算（如直播录制或获取信息失败）时返回 0
+func estimateTaskSize(task *DownloadTask, compute bool) int64 {
+	if task.Type == TaskTypeLive {
+		return 0
+	}
+
+	quality := resolveQuality(task.Quality)
+	key := sizeEstimateKey(task.URL, quality, task.Selection, task.Filter)
+	if estimated := getSizeEstimate(key); estimated > 0 || !compute {
+		return estimated
+	}
+
+	info, err := getVideoInfo(task.URL, quality, task.Selection, task.Filter)
+	if err != nil {
+		fmt.Printf("估算下载大小失败: %v\n", err)
+		return 0
+	}
+	saveSizeEstimate(key, info.EstimatedSize)
+	return info.EstimatedSize
+}
+
+// 检查任务的预估大小是否超过目标目录剩余空间，超过时返回提示信息
+// 返回的预估大小为 0 时表示无法估算，未检查剩余空间
+func checkEstimatedSpace(task *DownloadTask, compute bool) (string, int64) {
+	estimated := estimateTaskSize(task, compute)
+	if estimated <= 0 {
+		return "", 0
+	}
+
+	free, err := getFreeSpace(filepath.Join("audiobooks", task.SavePath))
+	if err != nil {
+		fmt.Printf("获取剩余空间失败: %v\n", err)
+		return "", estimated
+	}
+	if uint64(estimated) <= free {
+		return "", estimated
+	}
+	return fmt.Sprintf("预估大小 %s 超过剩余空间 %s", formatFileSize(estimated), formatFileSize(int64(free))), estimated
+}
+
+// 加入队列时没有预检查记录的任务，在开始下载前估算大小，超过剩余空间时返回错误
+func checkTaskSpaceOnStart(task *DownloadTask) error {
+	downloadMutex.Lock()
+	checkSpace := task.checkSpace
+	task.checkSpace = false
+	if checkSpace {
+		task.Progress.Status = "正在估算下载大小..."
+	}
+	downloadMutex.Unlock()
+	if !checkSpace {
+		return nil
+	}
+	broadcastProgress(task)
+
+	warning, estimated := checkEstimatedSpace(task, true)
+	if warning != "" {
+		return errors.New(warning)
+	}
+	if estimated <= 0 {
+		fmt.Printf("下载任务 %s 无法估算下载大小，未检查剩余空间\n", task.ID)
+	}
+	return nil
+}
+
+// 下载过程中检查磁盘空间和配额的间隔
+const diskCheckInterval = 30 * time.Second
+
+// 查找保存路径所属音频库的配额，返回音频库路径和配额字节数，没有配额时返回 0
+// 配额按子目录配置，保存路径位于多个配额目录下时使用最近的一个
+func findLibraryQuota(config *Config, savePath string) (string, int64) {
+	savePath = filepath.ToSlash(filepath.Clean(savePath))
+
+	library, quota := "", int64(0)
+	for dir, quotaMB := range config.LibraryQuotas {
+		if quotaMB <= 0 {
+			continue
+		}
+		dir = filepath.ToSlash(filepath.Clean(dir))
+		if dir != savePath && !strings.HasPrefix(savePath, dir+"/") && dir != "." {
+			continue
+		}
+		if quota == 0 || len(dir) > len(library) {
+			library, quota = dir, int64(quotaMB)*1024*1024
+		}
+	}
+	return library, quota
+}
+
+// 计算目录占用的空间
+func getDirSize(dir string) int64 {
+	var total int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
+
+// 检查剩余空间是否低于保留空间、音频库是否超出配额
+func checkDiskLimits(savePath string) error {
+	config, err := loadConfig()
+	if err != nil {
+		config = &defaultConfig
+	}
+
+	if config.MinFreeSpaceMB > 0 {
+		reserve := int64(config.MinFreeSpaceMB) * 1024 * 1024
+		free, err := getFreeSpace(filepath.Join("audiobooks", savePath))
+		if err != nil {
+			fmt.Printf("获取剩余空间失败: %v\n", err)
+		} else if int64(free) < reserve {
+			return fmt.Errorf("磁盘剩余空间不足: 剩余 %s，低于保留空间 %s", formatFileSize(int64(free)), formatFileSize(reserve))
+		}
+	}
+
+	if library, quota := findLibraryQuota(config, savePath); quota > 0 {
+		used := getDirSize(filepath.Join("audiobooks", library))
+		if used >= quota {
+			return fmt.Errorf("音频库 %s 已达到配额: 已使用 %s，配额 %s", filepath.Join("audiobooks", library), formatFileSize(used), formatFileSize(quota))
+		}
+	}
+
+	return nil
+}
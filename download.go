@@ -1,15 +1,15 @@
 package main
 
 import (
-	"bufio"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
 	"runtime"
 	"slices"
 	"strconv"
@@ -24,13 +24,35 @@ import (
 )
 
 var (
-	currentDownload *DownloadTask
-	downloadMutex   sync.RWMutex
-	wsConnections   []*websocket.Conn
-	wsConnMutex     sync.RWMutex
+	downloadTasks []*DownloadTask // 所有下载任务，按创建顺序排列
+	downloadMutex sync.RWMutex
+	wsConnections []*websocket.Conn
+	wsConnMutex   sync.RWMutex
 )
 
+// 下载任务状态
+const (
+	TaskStateQueued    = "queued"
+	TaskStateRunning   = "running"
+	TaskStatePaused    = "paused"
+	TaskStateCompleted = "completed"
+	TaskStateFailed    = "failed"
+	TaskStateStopped   = "stopped"
+)
+
+// 默认同时进行的下载任务数
+const defaultMaxConcurrentDownloads = 2
+
+// 默认停滞检测时间（秒）
+const defaultStallTimeoutSeconds = 300
+
 type DownloadTask struct {
+	ID             string    // 任务ID
+	Type           string    // 任务类型：download 或 live
+	State          string    // 任务状态
+	CreatedAt      time.Time // 创建时间
+	StartedAt      time.Time // 最近一次开始运行的时间
+	FinishedAt     time.Time // 结束时间
 	URL            string
 	SavePath       string
 	TitleRegex     string
@@ -41,9 +63,61 @@ type DownloadTask struct {
 	Quality        string    // 添加质量设置
 	RetryCount     int       // 添加重试次数
 	WriteThumbnail bool      // 添加缩略图设置
+	ResumePending  bool      // 下次启动时使用 --continue 继续下载
+
+	Request         DownloadRequest // 创建任务时的原始请求
+	DownloadedFiles []string        // 本次任务下载完成的音频文件
+	Items           []*DownloadItem // 播放列表条目状态，按序号排列
+
+	MaxDuration time.Duration // 任务总时限，0 表示不限
+	RetryItems  []int         // 仅重新下载这些序号的条目（失败条目重试）
+	UseArchive  bool          // 使用音频库的下载存档跳过已下载的视频
+	Selection   ItemSelection // 只下载选中的条目，为空表示全部
+	Filter      CatalogFilter // 按上传日期和标题关键字筛选条目
+
+	StartAt        time.Time // 计划开始时间，为空表示立即开始
+	SegmentMinutes int       // 直播录制的分段时长（分钟），0 表示不分段
+
+	BandwidthLimitKBps int           // 任务限速（KB/s），0 表示使用配置中的默认值
+	rateLimitKBps      int           // 当前 yt-dlp 进程使用的限速
+	liveRecorded       time.Duration // 之前各次连接已录制的时长
+	liveSession        time.Duration // 当前连接已录制的时长
+
+	downloadingID string    // 正在下载的条目ID，用于识别跳过的已存在文件
+	lastOutputAt  time.Time // 最近一次有输出或字节进度的时间，用于停滞检测
+	deadlineAt    time.Time // 任务截止时间
+	windowPaused  bool      // 因不在下载时段内被暂停，时段开始时自动继续
+	historyRun    int       // 已重试的次数，每次运行单独记录历史
+	checkSpace    bool      // 加入队列时没有预检查记录，开始下载前估算大小并检查剩余空间
+	runGeneration int       // 每次启动运行时递增，旧的运行结束时据此判断是否仍可更新任务状态
+	runActive     bool      // 运行协程尚未结束（如暂停后等待 yt-dlp 进程退出），期间不会再次启动
+
+	subscriptionID int // 由订阅创建时为订阅ID
+}
+
+// 任务信息（对外展示用）
+type TaskInfo struct {
+	ID             string            `json:"id"`
+	Type           string            `json:"type"`
+	State          string            `json:"state"`
+	URL            string            `json:"url"`
+	SavePath       string            `json:"save_path"`
+	TitleRegex     string            `json:"title_regex,omitempty"`
+	Quality        string            `json:"quality,omitempty"`
+	RetryCount     int               `json:"retry_count,omitempty"`
+	WriteThumbnail bool              `json:"write_thumbnail"`
+	BandwidthLimit int               `json:"bandwidth_limit_kbps,omitempty"`
+	CreatedAt      string            `json:"created_at"`
+	StartAt        string            `json:"start_at,omitempty"`
+	StartedAt      string            `json:"started_at,omitempty"`
+	FinishedAt     string            `json:"finished_at,omitempty"`
+	Progress       *DownloadProgress `json:"progress"`
+	Items          []DownloadItem    `json:"items"`
 }
 
 type DownloadProgress struct {
+	TaskID         string   `json:"taskId"`
+	TaskState      string   `json:"taskState"`
 	IsDownloading  bool     `json:"isDownloading"`
 	IsPaused       bool     `json:"isPaused"`
 	Progress       float64  `json:"progress"`     // 整体进度
@@ -69,6 +143,21 @@ type DownloadProgress struct {
 	WarningMessage string `json:"warningMessage"` // 警告信息
 	StartTime      string `json:"startTime"`      // 开始时间
 	Phase          string `json:"phase"`          // 当前阶段：extracting, downloading, merging, completed
+
+	// yt-dlp 结构化进度
+	CurrentID       string  `json:"currentId"`       // 当前条目ID
+	DownloadedBytes int64   `json:"downloadedBytes"` // 当前文件已下载字节数
+	TotalBytes      int64   `json:"totalBytes"`      // 当前文件总字节数（可能为估计值）
+	SpeedBytes      float64 `json:"speedBytes"`      // 下载速度（字节/秒）
+	ETASeconds      int     `json:"etaSeconds"`      // 预计剩余秒数
+
+	FailedItems []int `json:"failedItems,omitempty"` // 重试后仍失败的条目序号
+	RateLimit   int   `json:"rateLimit,omitempty"`   // 当前生效的限速（KB/s），0 表示不限速
+
+	// 直播录制进度
+	IsLive         bool   `json:"isLive,omitempty"`         // 是否为直播录制任务
+	Elapsed        string `json:"elapsed,omitempty"`        // 已录制时长
+	ElapsedSeconds int    `json:"elapsedSeconds,omitempty"` // 已录制秒数
 }
 
 // 获取yt-dlp可执行文件路径
@@ -246,34 +335,486 @@ func convertGBKToUTF8(s string) (string, error) {
 	return string(decoded), nil
 }
 
-// 开始下载
-func startDownload(task *DownloadTask) error {
-	// 检查是否已有正在进行的下载
+// 更新任务状态并记录对应时间，调用方需持有 downloadMutex
+func setTaskStateLocked(task *DownloadTask, state string) {
+	task.State = state
+	switch state {
+	case TaskStateRunning:
+		task.StartedAt = time.Now()
+		task.FinishedAt = time.Time{}
+	case TaskStateCompleted, TaskStateFailed, TaskStateStopped:
+		task.FinishedAt = time.Now()
+	}
+	if task.Progress != nil {
+		task.Progress.TaskState = state
+	}
+}
+
+// 格式化任务时间，零值返回空字符串
+func formatTaskTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// 生成任务信息快照
+func getTaskInfo(task *DownloadTask) TaskInfo {
+	progress := getTaskProgress(task)
+
+	downloadMutex.RLock()
+	defer downloadMutex.RUnlock()
+
+	return TaskInfo{
+		ID:             task.ID,
+		Type:           task.Type,
+		State:          task.State,
+		URL:            task.URL,
+		SavePath:       task.SavePath,
+		TitleRegex:     task.TitleRegex,
+		Quality:        task.Quality,
+		RetryCount:     task.RetryCount,
+		WriteThumbnail: task.WriteThumbnail,
+		BandwidthLimit: task.BandwidthLimitKBps,
+		CreatedAt:      formatTaskTime(task.CreatedAt),
+		StartAt:        formatTaskTime(task.StartAt),
+		StartedAt:      formatTaskTime(task.StartedAt),
+		FinishedAt:     formatTaskTime(task.FinishedAt),
+		Progress:       progress,
+		Items:          cloneItems(task.Items),
+	}
+}
+
+// 获取所有任务信息
+func listTaskInfos() []TaskInfo {
+	downloadMutex.RLock()
+	tasks := slices.Clone(downloadTasks)
+	downloadMutex.RUnlock()
+
+	infos := make([]TaskInfo, 0, len(tasks))
+	for _, task := range tasks {
+		infos = append(infos, getTaskInfo(task))
+	}
+	return infos
+}
+
+// 生成任务ID
+func newTaskID() string {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// 获取最大并发下载数
+func getMaxConcurrentDownloads() int {
+	config, err := loadConfig()
+	if err != nil || config.MaxConcurrentDownloads <= 0 {
+		return defaultMaxConcurrentDownloads
+	}
+	return config.MaxConcurrentDownloads
+}
+
+// 获取停滞检测时间
+func getStallTimeout() time.Duration {
+	seconds := defaultStallTimeoutSeconds
+	if config, err := loadConfig(); err == nil && config.StallTimeoutSeconds > 0 {
+		seconds = config.StallTimeoutSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// 根据下载请求创建任务
+func newDownloadTask(req DownloadRequest) (*DownloadTask, error) {
+	// 解析链接
+	parsedURL, err := parseURL(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("链接解析失败: %v", err)
+	}
+
+	selection, err := parseItemSelection(req.Items)
+	if err != nil {
+		return nil, fmt.Errorf("条目选择无效: %v", err)
+	}
+	if !selection.IsEmpty() {
+		parsedURL = stripPartSelector(parsedURL)
+	}
+
+	filter, err := parseCatalogFilter(req.TitleKeyword, req.DateAfter, req.DateBefore)
+	if err != nil {
+		return nil, fmt.Errorf("筛选条件无效: %v", err)
+	}
+
+	taskType := TaskTypeDownload
+	if _, ok := parseLiveRoomURL(parsedURL); ok {
+		taskType = TaskTypeLive
+	}
+	if req.SegmentMinutes < 0 {
+		return nil, fmt.Errorf("分段时长不能为负数")
+	}
+	if req.BandwidthLimitKBps < 0 {
+		return nil, fmt.Errorf("限速不能为负数")
+	}
+
+	var startAt time.Time
+	if req.StartAt != "" {
+		if startAt, err = parseStartAt(req.StartAt); err != nil {
+			return nil, err
+		}
+	}
+
+	return &DownloadTask{
+		Type:               taskType,
+		URL:                parsedURL,
+		SavePath:           req.SavePath,
+		TitleRegex:         req.TitleRegex,
+		Quality:            req.Quality,
+		RetryCount:         req.RetryCount,
+		WriteThumbnail:     req.WriteThumbnail,
+		MaxDuration:        time.Duration(req.MaxDurationMinutes) * time.Minute,
+		UseArchive:         isDownloadArchiveEnabled() && !req.IgnoreArchive,
+		Selection:          selection,
+		Filter:             filter,
+		StartAt:            startAt,
+		SegmentMinutes:     req.SegmentMinutes,
+		BandwidthLimitKBps: req.BandwidthLimitKBps,
+		Request:            req,
+	}, nil
+}
+
+// 将下载任务加入队列
+func enqueueDownload(task *DownloadTask) {
+	downloadMutex.Lock()
+	task.ID = newTaskID()
+	task.State = TaskStateQueued
+	task.CreatedAt = time.Now()
+	task.Progress = &DownloadProgress{
+		TaskID:         task.ID,
+		TaskState:      TaskStateQueued,
+		Status:         "排队等待中...",
+		LastActivity:   "任务已加入队列",
+		CompletedFiles: make([]string, 0),
+		Phase:          "queued",
+	}
+	downloadTasks = append(downloadTasks, task)
+	downloadMutex.Unlock()
+
+	fmt.Printf("下载任务已加入队列: ID=%s, URL=%s\n", task.ID, task.URL)
+	broadcastProgress(task)
+
+	scheduleDownloads()
+}
+
+// 调度排队中的任务，直到达到并发上限
+func scheduleDownloads() {
+	limit := getMaxConcurrentDownloads()
+	windows := getDownloadWindows()
+	now := time.Now()
+
+	downloadMutex.Lock()
+	// 暂停或停止后仍在等待进程退出的任务继续占用名额
+	running := 0
+	for _, task := range downloadTasks {
+		if task.State == TaskStateRunning || task.runActive {
+			running++
+		}
+	}
+
+	var toStart, waiting []*DownloadTask
+	var generations []int
+	for _, task := range downloadTasks {
+		if running >= limit {
+			break
+		}
+		if task.State != TaskStateQueued {
+			continue
+		}
+		// 上一次运行的进程尚未退出，等待其结束后再启动，避免两个进程同时下载同一任务
+		if task.runActive {
+			if reason := "等待上一次下载进程退出..."; task.Progress.Status != reason {
+				task.Progress.Status = reason
+				waiting = append(waiting, task)
+			}
+			continue
+		}
+		// 未到计划开始时间或不在下载时段内的任务继续排队
+		if reason := taskWaitReasonLocked(task, windows, now); reason != "" {
+			if task.Progress.Status != reason {
+				task.Progress.Status = reason
+				task.Progress.LastActivity = reason
+				waiting = append(waiting, task)
+			}
+			continue
+		}
+		setTaskStateLocked(task, TaskStateRunning)
+		task.IsRunning = true
+		task.runGeneration++
+		task.runActive = true
+		toStart = append(toStart, task)
+		generations = append(generations, task.runGeneration)
+		running++
+	}
+	downloadMutex.Unlock()
+
+	for _, task := range waiting {
+		go broadcastProgress(task)
+	}
+	for i, task := range toStart {
+		go runDownloadTask(task, generations[i])
+	}
+}
+
+// 执行下载任务，结束后根据任务状态记录结果并调度下一个任务
+// generation 为启动时的运行序号，只有仍是最近一次运行时才更新任务的最终状态
+func runDownloadTask(task *DownloadTask, generation int) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("下载任务 %s panic: %v\n", task.ID, r)
+		}
+		downloadMutex.Lock()
+		if task.runGeneration == generation {
+			task.runActive = false
+		}
+		downloadMutex.Unlock()
+		scheduleDownloads()
+	}()
+
+	downloadMutex.RLock()
+	isContinue := task.ResumePending
+	downloadMutex.RUnlock()
+
+	if !isContinue {
+		// 添加任务开始记录
+		addTaskToHistory(task, "downloading", "")
+	}
+
+	var err error
+	if task.Type == TaskTypeLive {
+		err = recordLive(task)
+	} else {
+		err = runWithItemRetries(task)
+	}
+
 	downloadMutex.Lock()
-	if currentDownload != nil && currentDownload.IsRunning {
+	if task.State != TaskStateRunning || task.runGeneration != generation {
+		// 任务已被暂停或停止，由对应操作负责更新状态
+		downloadMutex.Unlock()
+		return
+	}
+	task.IsRunning = false
+	task.Cmd = nil
+	task.RetryItems = nil
+	if err != nil {
+		finalizeItemsLocked(task, err.Error())
+		setTaskStateLocked(task, TaskStateFailed)
+		task.Progress.Status = "下载失败"
+		task.Progress.Phase = "error"
+		task.Progress.ErrorMessage = err.Error()
+	} else {
+		finalizeItemsLocked(task, "下载未完成")
+		setTaskStateLocked(task, TaskStateCompleted)
+		task.Progress.Progress = 100.0
+		task.Progress.Status = "下载完成"
+		task.Progress.Phase = "completed"
+		if task.Type == TaskTypeLive {
+			task.Progress.Status = "录制完成"
+		}
+		if skipped, reason := skipUnprocessedItemsLocked(task); skipped > 0 {
+			task.Progress.Status = fmt.Sprintf("下载完成，%d 个条目%s，已跳过", skipped, reason)
+		}
+	}
+	task.Progress.IsDownloading = false
+	downloadMutex.Unlock()
+
+	if err != nil {
+		fmt.Printf("下载任务 %s 失败: %v\n", task.ID, err)
+		// 添加失败记录
+		addTaskToHistory(task, "failed", err.Error())
+	} else {
+		fmt.Printf("下载任务 %s 成功完成\n", task.ID)
+		// 添加成功记录
+		addTaskToHistory(task, "completed", "")
+	}
+
+	broadcastProgress(task)
+	broadcastItems(task)
+}
+
+// 获取失败条目的重试次数和初始间隔
+func getItemRetryPolicy() (int, time.Duration) {
+	config, err := loadConfig()
+	if err != nil {
+		config = &defaultConfig
+	}
+	attempts := max(config.ItemRetryAttempts, 0)
+	delay := time.Duration(min(max(config.ItemRetryDelaySeconds, 1), int(maxItemRetryDelay/time.Second))) * time.Second
+	return attempts, delay
+}
+
+// 重试间隔上限
+const maxItemRetryDelay = 30 * time.Minute
+
+// 计算第 attempt 次重试的等待时间，每次翻倍，达到上限后不再增加
+func itemRetryBackoff(delay time.Duration, attempt int) time.Duration {
+	wait := min(delay, maxItemRetryDelay)
+	for i := 1; i < attempt && wait < maxItemRetryDelay; i++ {
+		wait = min(wait*2, maxItemRetryDelay)
+	}
+	return wait
+}
+
+// 获取失败条目的序号，retryableOnly 为 true 时不包括重试也无法成功的条目，调用方需持有 downloadMutex
+func failedItemIndicesLocked(task *DownloadTask, retryableOnly bool) []int {
+	var indices []int
+	for _, item := range task.Items {
+		if item.Status == ItemStateFailed && !(retryableOnly && item.noRetry) {
+			indices = append(indices, item.Index)
+		}
+	}
+	return indices
+}
+
+// 格式化条目序号列表，用于 --playlist-items 和错误信息
+func formatItemIndices(indices []int) string {
+	parts := make([]string, 0, len(indices))
+	for _, index := range indices {
+		parts = append(parts, strconv.Itoa(index))
+	}
+	return strings.Join(parts, ",")
+}
+
+// 等待重试间隔，期间任务被暂停或停止时返回 false
+func waitForRetry(task *DownloadTask, delay time.Duration) bool {
+	deadline := time.Now().Add(delay)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for time.Now().Before(deadline) {
+		<-ticker.C
+		downloadMutex.RLock()
+		running := task.State == TaskStateRunning
+		downloadMutex.RUnlock()
+		if !running {
+			return false
+		}
+	}
+	return true
+}
+
+// 执行下载，结束后对失败的条目按指数退避重新下载
+func runWithItemRetries(task *DownloadTask) error {
+	err := runDownloadProcess(task)
+
+	maxAttempts, delay := getItemRetryPolicy()
+	for attempt := 1; ; attempt++ {
+		downloadMutex.Lock()
+		if task.State != TaskStateRunning {
+			downloadMutex.Unlock()
+			return err
+		}
+		failed := failedItemIndicesLocked(task, false)
+		task.Progress.FailedItems = failed
+		if len(failed) == 0 {
+			downloadMutex.Unlock()
+			return err
+		}
+		retryable := failedItemIndicesLocked(task, true)
+		if len(retryable) == 0 {
+			downloadMutex.Unlock()
+			return fmt.Errorf("%d 个条目下载失败: 第 %s 项", len(failed), formatItemIndices(failed))
+		}
+		if attempt > maxAttempts {
+			downloadMutex.Unlock()
+			return fmt.Errorf("%d 个条目下载失败（已重试 %d 次）: 第 %s 项", len(failed), maxAttempts, formatItemIndices(failed))
+		}
+
+		wait := itemRetryBackoff(delay, attempt)
+		task.Progress.Status = fmt.Sprintf("%d 个条目下载失败，%v 后进行第 %d/%d 次重试", len(retryable), wait, attempt, maxAttempts)
+		task.Progress.Phase = "retrying"
+		task.Cmd = nil
+		downloadMutex.Unlock()
+
+		fmt.Printf("下载任务 %s 有 %d 个条目失败，%v 后重试: %s\n", task.ID, len(retryable), wait, formatItemIndices(retryable))
+		broadcastProgress(task)
+
+		if !waitForRetry(task, wait) {
+			return err
+		}
+
+		downloadMutex.Lock()
+		if task.State != TaskStateRunning {
+			downloadMutex.Unlock()
+			return err
+		}
+		for _, item := range task.Items {
+			if item.Status == ItemStateFailed && !item.noRetry {
+				item.Status = ItemStatePending
+			}
+		}
+		task.RetryItems = retryable
+		task.ResumePending = true
 		downloadMutex.Unlock()
-		return fmt.Errorf("已有下载任务正在进行中")
+
+		broadcastItems(task)
+		err = runDownloadProcess(task)
 	}
+}
 
-	// 设置当前下载任务
+// 开始下载
+func startDownload(task *DownloadTask) error {
+	// 设置任务运行状态
+	downloadMutex.Lock()
 	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	task.Cancel = cancel
-	task.IsRunning = true
-	task.Progress = &DownloadProgress{
-		IsDownloading:  true,
-		Status:         "准备开始下载...",
-		LastActivity:   "初始化下载任务",
-		CompletedFiles: make([]string, 0),
-		StartTime:      time.Now().Format("2006-01-02 15:04:05"),
-		Phase:          "initializing",
+	isContinue := task.ResumePending
+	task.ResumePending = false
+	task.lastOutputAt = time.Now()
+	if task.MaxDuration > 0 && task.deadlineAt.IsZero() {
+		task.deadlineAt = time.Now().Add(task.MaxDuration)
+	}
+	deadlineAt := task.deadlineAt
+	if isContinue {
+		task.Progress.IsDownloading = true
+		task.Progress.IsPaused = false
+		task.Progress.Status = "继续下载中..."
+		task.Progress.Phase = "downloading"
+		task.Progress.LastActivity = "用户继续下载"
+	} else {
+		task.DownloadedFiles = nil
+		task.Items = nil
+		task.Progress = &DownloadProgress{
+			TaskID:         task.ID,
+			TaskState:      task.State,
+			IsDownloading:  true,
+			Status:         "准备开始下载...",
+			LastActivity:   "初始化下载任务",
+			CompletedFiles: make([]string, 0),
+			StartTime:      time.Now().Format("2006-01-02 15:04:05"),
+			Phase:          "initializing",
+		}
 	}
-	currentDownload = task
 	downloadMutex.Unlock()
 
 	// 立即广播初始状态
-	broadcastProgress()
+	broadcastProgress(task)
+
+	fmt.Printf("开始下载任务: ID=%s, URL=%s, SavePath=%s, TitleRegex=%s\n", task.ID, task.URL, task.SavePath, task.TitleRegex)
+
+	// 磁盘空间不足或超出配额时暂停任务，释放空间后可继续
+	if err := checkDiskLimits(task.SavePath); err != nil {
+		fmt.Printf("下载任务 %s 暂停: %v\n", task.ID, err)
+		pauseTaskWithReason(task, err.Error())
+		return err
+	}
 
-	fmt.Printf("开始下载任务: URL=%s, SavePath=%s, TitleRegex=%s\n", task.URL, task.SavePath, task.TitleRegex)
+	// 预估大小超过剩余空间时暂停任务，确认空间足够后可继续
+	if err := checkTaskSpaceOnStart(task); err != nil {
+		fmt.Printf("下载任务 %s 暂停: %v\n", task.ID, err)
+		pauseTaskWithReason(task, err.Error())
+		return err
+	}
 
 	// 创建保存目录
 	savePath := filepath.Join("audiobooks", task.SavePath)
@@ -283,22 +824,24 @@ func startDownload(task *DownloadTask) error {
 	fmt.Printf("创建目录成功: %s\n", savePath)
 
 	// 扫描已存在的音频文件并预加载到完成列表
-	fmt.Println("扫描已存在的音频文件...")
-	existingFiles := scanExistingAudioFiles(savePath)
-	if len(existingFiles) > 0 {
-		downloadMutex.Lock()
-		// 只保留最近3个文件用于显示
-		if len(existingFiles) > 3 {
-			currentDownload.Progress.CompletedFiles = existingFiles[len(existingFiles)-3:]
-		} else {
-			currentDownload.Progress.CompletedFiles = existingFiles
-		}
-		currentDownload.Progress.Status = fmt.Sprintf("发现 %d 个已存在的音频文件", len(existingFiles))
-		downloadMutex.Unlock()
+	if !isContinue {
+		fmt.Println("扫描已存在的音频文件...")
+		existingFiles := scanExistingAudioFiles(savePath)
+		if len(existingFiles) > 0 {
+			downloadMutex.Lock()
+			// 只保留最近3个文件用于显示
+			if len(existingFiles) > 3 {
+				task.Progress.CompletedFiles = existingFiles[len(existingFiles)-3:]
+			} else {
+				task.Progress.CompletedFiles = existingFiles
+			}
+			task.Progress.Status = fmt.Sprintf("发现 %d 个已存在的音频文件", len(existingFiles))
+			downloadMutex.Unlock()
 
-		// 广播更新状态
-		broadcastProgress()
-		fmt.Printf("预加载了 %d 个已存在的音频文件到完成列表\n", len(currentDownload.Progress.CompletedFiles))
+			// 广播更新状态
+			broadcastProgress(task)
+			fmt.Printf("预加载了 %d 个已存在的音频文件到完成列表\n", len(existingFiles))
+		}
 	}
 
 	// 确保 yt-dlp 有执行权限
@@ -306,9 +849,15 @@ func startDownload(task *DownloadTask) error {
 		return fmt.Errorf("yt-dlp 权限检查失败: %v", err)
 	}
 
+	// 按当前并发任务数计算限速
+	rateLimit := getTaskRateLimit(task)
+	downloadMutex.Lock()
+	task.rateLimitKBps = rateLimit
+	task.Progress.RateLimit = rateLimit
+	downloadMutex.Unlock()
+
 	// 构建yt-dlp命令
-	cmd := buildYtDlpCommand(task, false) // 初始下载不使用continue
-	task.Cmd = cmd                        // 保存命令引用
+	cmd := buildYtDlpCommand(task, isContinue)
 
 	// 设置环境变量确保UTF-8编码
 	cmd.Env = append(os.Environ(),
@@ -320,53 +869,134 @@ func startDownload(task *DownloadTask) error {
 	// 显示执行的命令
 	fmt.Printf("执行命令: %s\n", formatCommand(cmd))
 
-	// 创建输出管道
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("创建stdout管道失败: %v", err)
-	}
-
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return fmt.Errorf("创建stderr管道失败: %v", err)
-	}
+	// 创建输出管道：Wait 会等待输出全部被读取，避免丢失进程结束前的最后几行
+	// stdout 和 stderr 共用同一管道，保证错误信息与条目输出的先后顺序
+	output, outputWriter := io.Pipe()
+	cmd.Stdout = outputWriter
+	cmd.Stderr = outputWriter
+	// 子进程（如 ffmpeg）可能在 yt-dlp 被终止后仍占用输出管道
+	cmd.WaitDelay = 5 * time.Second
 
 	// 启动命令
 	if err := cmd.Start(); err != nil {
 		return fmt.Errorf("启动yt-dlp失败: %v", err)
 	}
 
+	downloadMutex.Lock()
+	task.Cmd = cmd // 保存命令引用
+	cancelled := task.State != TaskStateRunning
+	downloadMutex.Unlock()
+
+	// 启动期间任务已被停止或暂停
+	if cancelled {
+		output.Close()
+		cmd.Process.Kill()
+		cmd.Wait()
+		return fmt.Errorf("下载被取消")
+	}
+
 	fmt.Printf("yt-dlp已启动，PID: %d\n", cmd.Process.Pid)
 
 	// 启动输出解析goroutine
-	go parseOutput(stdout, "stdout")
-	go parseOutput(stderr, "stderr")
+	parsed := make(chan struct{})
+	go func() {
+		defer close(parsed)
+		parseOutput(task, output, "yt-dlp")
+	}()
 
 	// 启动状态监控
-	go monitorDownload(ctx)
+	go monitorDownload(ctx, task)
 
-	// 等待命令完成
+	// 等待命令完成，并等待所有输出解析完毕
 	done := make(chan error, 1)
 	go func() {
-		done <- cmd.Wait()
+		err := cmd.Wait()
+		outputWriter.Close()
+		<-parsed
+		done <- err
 	}()
 
-	// 设置超时
-	timeout := time.After(10 * time.Minute)
+	// 停滞检测：超过停滞时间没有任何输出或字节进度时终止进程
+	stallTimeout := getStallTimeout()
+	watchdog := time.NewTicker(min(stallTimeout/4, 15*time.Second))
+	defer watchdog.Stop()
+
+	// 任务总时限
+	var deadline <-chan time.Time
+	if !deadlineAt.IsZero() {
+		timer := time.NewTimer(time.Until(deadlineAt))
+		defer timer.Stop()
+		deadline = timer.C
+	}
 
-	select {
-	case err := <-done:
-		fmt.Printf("yt-dlp进程结束: %v\n", err)
-		return err
-	case <-timeout:
-		fmt.Println("下载超时，终止进程")
-		cmd.Process.Kill()
-		return fmt.Errorf("下载超时")
-	case <-ctx.Done():
-		fmt.Println("下载被取消，终止进程")
-		cmd.Process.Kill()
-		return fmt.Errorf("下载被取消")
+	// 下载过程中定期检查磁盘空间和配额
+	diskCheck := time.NewTicker(diskCheckInterval)
+	defer diskCheck.Stop()
+
+	// 全局或任务限速变化时以新的限速继续下载
+	rateCheck := time.NewTicker(bandwidthCheckInterval)
+	defer rateCheck.Stop()
+	rateChanged := false
+
+	for {
+		select {
+		case err := <-done:
+			fmt.Printf("yt-dlp进程结束: %v\n", err)
+			if rateChanged && err != nil {
+				return errBandwidthChanged
+			}
+			// 磁盘写满导致的失败同样暂停任务，避免留下无法续传的失败记录
+			if err != nil {
+				if limitErr := checkDiskLimits(task.SavePath); limitErr != nil {
+					pauseTaskWithReason(task, limitErr.Error())
+					return limitErr
+				}
+			}
+			return err
+		case <-diskCheck.C:
+			if err := checkDiskLimits(task.SavePath); err != nil {
+				fmt.Printf("下载任务 %s 暂停: %v\n", task.ID, err)
+				pauseTaskWithReason(task, err.Error())
+			}
+		case <-rateCheck.C:
+			if newLimit := getTaskRateLimit(task); !rateChanged && newLimit != rateLimit {
+				if err := interruptForBandwidthChange(task, rateLimit, newLimit); err != nil {
+					fmt.Printf("调整限速失败: %v\n", err)
+					continue
+				}
+				rateChanged = true
+			}
+		case <-watchdog.C:
+			downloadMutex.RLock()
+			idle := time.Since(task.lastOutputAt)
+			downloadMutex.RUnlock()
+			if idle < stallTimeout {
+				continue
+			}
+			fmt.Printf("下载已停滞 %v，终止进程\n", idle.Round(time.Second))
+			cmd.Process.Kill()
+			<-done
+			return fmt.Errorf("下载停滞超过 %v 没有任何进展，已终止", stallTimeout)
+		case <-deadline:
+			fmt.Println("下载超过任务时限，终止进程")
+			cmd.Process.Kill()
+			<-done
+			return fmt.Errorf("下载超过任务时限 %v，已终止", task.MaxDuration)
+		case <-ctx.Done():
+			fmt.Println("下载被取消，终止进程")
+			cmd.Process.Kill()
+			<-done
+			return fmt.Errorf("下载被取消")
+		}
+	}
+}
+
+// 获取实际使用的画质，未指定时下载最佳音频
+func resolveQuality(quality string) string {
+	if quality == "" {
+		return "bestaudio/best"
 	}
+	return quality
 }
 
 // 构建yt-dlp命令
@@ -374,10 +1004,7 @@ func buildYtDlpCommand(task *DownloadTask, isContinue bool) *exec.Cmd {
 	ytDlpPath := getYtDlpPath()
 
 	// 基础参数
-	quality := "bestaudio/best"
-	if task.Quality != "" {
-		quality = task.Quality
-	}
+	quality := resolveQuality(task.Quality)
 
 	retryCount := "5"
 	if task.RetryCount > 0 {
@@ -389,8 +1016,17 @@ func buildYtDlpCommand(task *DownloadTask, isContinue bool) *exec.Cmd {
 		"-P", filepath.Join("audiobooks", task.SavePath),
 		"--extractor-retries", retryCount,
 		"--newline",
-		"--progress-template", "download:%(progress._percent_str)s %(progress._speed_str)s",
 		"--no-warnings",
+		// 使用结构化输出代替文本解析：--print 会启用安静模式，
+		// 因此需要显式开启进度输出并关闭模拟下载
+		"--no-simulate",
+		"--progress",
+		// 安静模式下被筛选条件或下载存档排除的条目没有任何输出，
+		// 连续排除大量条目时会被误判为停滞，因此关闭安静模式保留这些提示
+		"--no-quiet",
+		"--progress-template", ytDlpProgressTemplate,
+		"--print", ytDlpItemTemplate,
+		"--print", ytDlpFileTemplate,
 	}
 
 	// 添加缩略图选项
@@ -398,6 +1034,27 @@ func buildYtDlpCommand(task *DownloadTask, isContinue bool) *exec.Cmd {
 		args = append(args, "--write-thumbnail")
 	}
 
+	// 音频区单曲和歌单保存歌词、作者和封面
+	args = append(args, audioZoneArgs(task.URL)...)
+
+	// 使用下载存档按视频ID去重，跳过音频库中已下载过的视频
+	if task.UseArchive {
+		args = append(args, "--download-archive", getDownloadArchivePath(task.SavePath))
+	}
+
+	// 只下载指定的条目；重试失败条目时只下载失败的条目
+	if len(task.RetryItems) > 0 {
+		args = append(args, "--playlist-items", formatItemIndices(task.RetryItems))
+	} else if items := task.Selection.PlaylistItems(); items != "" {
+		args = append(args, "--playlist-items", items)
+	}
+	args = append(args, task.Filter.YtDlpArgs(task.Selection.MatchFilters())...)
+
+	// 限速
+	if task.rateLimitKBps > 0 {
+		args = append(args, "--limit-rate", fmt.Sprintf("%dK", task.rateLimitKBps))
+	}
+
 	// 添加继续下载选项
 	if isContinue {
 		args = append(args, "--continue")
@@ -411,6 +1068,9 @@ func buildYtDlpCommand(task *DownloadTask, isContinue bool) *exec.Cmd {
 		fmt.Printf("使用cookies文件: %s\n", cookiesPath)
 	}
 
+	// 代理
+	args = append(args, ytDlpProxyArgs()...)
+
 	// 添加输出格式
 	outputFormat := "%(title)s.%(ext)s"
 	if task.TitleRegex != "" {
@@ -440,895 +1100,339 @@ func formatCommand(cmd *exec.Cmd) string {
 	return strings.Join(parts, " ")
 }
 
-// 解析输出
-func parseOutput(pipe io.Reader, source string) {
-	// 确保UTF-8编码处理
-	scanner := bufio.NewScanner(pipe)
-	scanner.Split(bufio.ScanLines)
-
-	// 设置更大的缓冲区以处理长行
-	const maxCapacity = 1024 * 1024 // 1MB
-	buf := make([]byte, maxCapacity)
-	scanner.Buffer(buf, maxCapacity)
-
-	lineCount := 0
-
-	// 进度解析正则表达式 - 更全面的匹配
-	progressRegex := regexp.MustCompile(`download:(\d+\.?\d*)%\s+(.*)`)
-	progressRegex2 := regexp.MustCompile(`\[download\]\s+(\d+\.?\d*)%\s+of\s+([^\s]+)\s+at\s+([^\s]+)\s+ETA\s+([^\s]+)`)
-	progressRegex3 := regexp.MustCompile(`\[download\]\s+(\d+\.?\d*)%\s+of\s+([^\s]+)\s+at\s+([^\s]+)`)
-	// 新增：匹配简单的进度格式（如 "  30.1%  604.19KiB/s"）
-	progressRegex4 := regexp.MustCompile(`^\s*(\d+\.?\d*)%\s+(.+)$`)
-
-	// 项目和播放列表信息
-	itemRegex := regexp.MustCompile(`\[download\] Downloading item (\d+) of (\d+)`)
-	playlistRegex := regexp.MustCompile(`\[.*\] Playlist .*: Downloading (\d+) items`)
-	playlistTitleRegex := regexp.MustCompile(`\[.*\] (.+): Downloading (\d+) items`)
-
-	// 提取和处理信息
-	extractingRegex := regexp.MustCompile(`\[.*\] Extracting URL: (.*)`)
-	extractorRegex := regexp.MustCompile(`\[(.+)\] (.+): (.*)`)
-
-	// 文件信息
-	fileInfoRegex := regexp.MustCompile(`\[info\] (.+): Downloading \d+ format\(s\)`)
-	titleRegex := regexp.MustCompile(`\[info\] (.+): (.*)`)
-	downloadingRegex := regexp.MustCompile(`\[download\] (.+\.(m4a|mp3|opus|webm)) has already been downloaded`)
-	destinationRegex := regexp.MustCompile(`\[download\] Destination: (.+)`)
-
-	// 新增：更全面的文件完成检测
-	mergeCompleteRegex := regexp.MustCompile(`\[Merger\] Merging formats into "(.+)"`)
-	ffmpegCompleteRegex := regexp.MustCompile(`\[ffmpeg\] Merging formats into "(.+)"`)
-
-	// 完成状态
-	completedRegex := regexp.MustCompile(`^100\.0%\s+(.+)`)
-	finishedRegex := regexp.MustCompile(`\[download\] 100% of .+ in \d+:\d+`)
-	mergeRegex := regexp.MustCompile(`\[Merger\] Merging formats into "(.+)"`)
-
-	// 错误和警告
-	errorRegex := regexp.MustCompile(`ERROR: (.*)`)
-	warningRegex := regexp.MustCompile(`WARNING: (.*)`)
-
-	// 元数据信息
-	metadataRegex := regexp.MustCompile(`\[info\] (.+): (.+)`)
-	durationRegex := regexp.MustCompile(`duration:\s*(\d+:\d+:\d+|\d+:\d+)`)
-	uploaderRegex := regexp.MustCompile(`uploader:\s*(.+)`)
-	viewCountRegex := regexp.MustCompile(`view_count:\s*(\d+)`)
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		lineCount++
-
-		// 清理和验证UTF-8字符串
-		line = cleanUTF8String(line)
-
-		fmt.Printf("[%s] 输出[%d]: %s\n", source, lineCount, line)
-
-		downloadMutex.Lock()
-		if currentDownload == nil || !currentDownload.IsRunning {
-			downloadMutex.Unlock()
-			break
-		}
-
-		// 解析进度 - 支持多种格式
-		if match := progressRegex.FindStringSubmatch(line); len(match) > 2 {
-			if fileProgress, err := strconv.ParseFloat(match[1], 64); err == nil {
-				// 计算整体进度
-				var overallProgress float64
-				if currentDownload.Progress.TotalCount > 0 && currentDownload.Progress.CurrentIndex > 0 {
-					// 播放列表进度计算：(已完成项目数 + 当前项目进度) / 总项目数 * 100
-					completedItems := float64(currentDownload.Progress.CurrentIndex - 1) // 已完成的项目数
-					currentItemProgress := fileProgress / 100.0                          // 当前项目进度 (0-1)
-					overallProgress = (completedItems + currentItemProgress) / float64(currentDownload.Progress.TotalCount) * 100.0
-				} else {
-					// 单个文件下载，直接使用文件进度
-					overallProgress = fileProgress
-				}
-
-				currentDownload.Progress.Progress = overallProgress
-				currentDownload.Progress.FileProgress = fileProgress
-				currentDownload.Progress.Speed = strings.TrimSpace(match[2])
+// 监控下载状态
+func monitorDownload(ctx context.Context, task *DownloadTask) {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
 
-				if currentDownload.Progress.TotalCount > 1 {
-					currentDownload.Progress.Status = fmt.Sprintf("整体进度: %.1f%% (当前文件: %.1f%%)", overallProgress, fileProgress)
-				} else {
-					currentDownload.Progress.Status = fmt.Sprintf("下载中: %.1f%%", fileProgress)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			downloadMutex.RLock()
+			if task.IsRunning {
+				progress := task.Progress
+				if progress != nil {
+					fmt.Printf("=== 下载状态 [%s] ===\n", task.ID)
+					fmt.Printf("状态: %s\n", progress.Status)
+					fmt.Printf("进度: %.1f%%\n", progress.Progress)
+					fmt.Printf("速度: %s\n", progress.Speed)
+					fmt.Printf("项目: %d/%d\n", progress.CurrentIndex, progress.TotalCount)
+					fmt.Printf("===============\n")
 				}
-				currentDownload.Progress.Phase = "downloading"
 			}
-		} else if match := progressRegex4.FindStringSubmatch(line); len(match) > 2 {
-			// 匹配简单的进度格式（如 "  30.1%  604.19KiB/s"）
-			if fileProgress, err := strconv.ParseFloat(match[1], 64); err == nil {
-				// 检查是否是已跳过文件，避免覆盖跳过状态
-				isSkippedFile := currentDownload.Progress.Phase == "skipped"
-
-				// 计算整体进度
-				var overallProgress float64
-				if currentDownload.Progress.TotalCount > 0 && currentDownload.Progress.CurrentIndex > 0 {
-					// 播放列表进度计算：(已完成项目数 + 当前项目进度) / 总项目数 * 100
-					completedItems := float64(currentDownload.Progress.CurrentIndex - 1) // 已完成的项目数
-					currentItemProgress := fileProgress / 100.0                          // 当前项目进度 (0-1)
-					overallProgress = (completedItems + currentItemProgress) / float64(currentDownload.Progress.TotalCount) * 100.0
-				} else {
-					// 单个文件下载，直接使用文件进度
-					overallProgress = fileProgress
-				}
-
-				currentDownload.Progress.Progress = overallProgress
-				currentDownload.Progress.FileProgress = fileProgress
-
-				// 只有不是跳过文件时才更新速度和状态
-				if !isSkippedFile {
-					currentDownload.Progress.Speed = strings.TrimSpace(match[2])
-					if currentDownload.Progress.TotalCount > 1 {
-						currentDownload.Progress.Status = fmt.Sprintf("整体进度: %.1f%% (当前文件: %.1f%%)", overallProgress, fileProgress)
-					} else {
-						currentDownload.Progress.Status = fmt.Sprintf("下载中: %.1f%%", fileProgress)
-					}
-					currentDownload.Progress.Phase = "downloading"
-				}
-
-				fmt.Printf("解析进度: 文件进度=%.1f%%, 整体进度=%.1f%%, 速度=%s, 跳过=%v\n", fileProgress, overallProgress, strings.TrimSpace(match[2]), isSkippedFile)
-			}
-		} else if match := progressRegex2.FindStringSubmatch(line); len(match) > 4 {
-			// [download] 50.0% of 10.5MiB at 1.2MiB/s ETA 00:05
-			if fileProgress, err := strconv.ParseFloat(match[1], 64); err == nil {
-				// 计算整体进度
-				var overallProgress float64
-				if currentDownload.Progress.TotalCount > 0 && currentDownload.Progress.CurrentIndex > 0 {
-					completedItems := float64(currentDownload.Progress.CurrentIndex - 1)
-					currentItemProgress := fileProgress / 100.0
-					overallProgress = (completedItems + currentItemProgress) / float64(currentDownload.Progress.TotalCount) * 100.0
-				} else {
-					overallProgress = fileProgress
-				}
-
-				currentDownload.Progress.Progress = overallProgress
-				currentDownload.Progress.FileProgress = fileProgress
-				currentDownload.Progress.Speed = strings.TrimSpace(match[3])
-				currentDownload.Progress.ETA = strings.TrimSpace(match[4])
-				currentDownload.Progress.FileSize = strings.TrimSpace(match[2])
-
-				if currentDownload.Progress.TotalCount > 1 {
-					currentDownload.Progress.Status = fmt.Sprintf("整体进度: %.1f%% (当前文件: %.1f%%, 大小: %s)", overallProgress, fileProgress, match[2])
-				} else {
-					currentDownload.Progress.Status = fmt.Sprintf("下载中: %.1f%% (大小: %s)", fileProgress, match[2])
-				}
-				currentDownload.Progress.Phase = "downloading"
-			}
-		} else if match := progressRegex3.FindStringSubmatch(line); len(match) > 3 {
-			// [download] 50.0% of 10.5MiB at 1.2MiB/s
-			if fileProgress, err := strconv.ParseFloat(match[1], 64); err == nil {
-				// 计算整体进度
-				var overallProgress float64
-				if currentDownload.Progress.TotalCount > 0 && currentDownload.Progress.CurrentIndex > 0 {
-					completedItems := float64(currentDownload.Progress.CurrentIndex - 1)
-					currentItemProgress := fileProgress / 100.0
-					overallProgress = (completedItems + currentItemProgress) / float64(currentDownload.Progress.TotalCount) * 100.0
-				} else {
-					overallProgress = fileProgress
-				}
-
-				currentDownload.Progress.Progress = overallProgress
-				currentDownload.Progress.FileProgress = fileProgress
-				currentDownload.Progress.Speed = strings.TrimSpace(match[3])
-				currentDownload.Progress.FileSize = strings.TrimSpace(match[2])
-
-				if currentDownload.Progress.TotalCount > 1 {
-					currentDownload.Progress.Status = fmt.Sprintf("整体进度: %.1f%% (当前文件: %.1f%%, 大小: %s)", overallProgress, fileProgress, match[2])
-				} else {
-					currentDownload.Progress.Status = fmt.Sprintf("下载中: %.1f%% (大小: %s)", fileProgress, match[2])
-				}
-				currentDownload.Progress.Phase = "downloading"
-			}
-		}
-
-		// 解析播放列表进度
-		if match := itemRegex.FindStringSubmatch(line); len(match) > 2 {
-			if current, err := strconv.Atoi(match[1]); err == nil {
-				if total, err := strconv.Atoi(match[2]); err == nil {
-					currentDownload.Progress.CurrentIndex = current
-					currentDownload.Progress.TotalCount = total
-					currentDownload.Progress.Status = fmt.Sprintf("下载项目: %d/%d", current, total)
-				}
-			}
-		}
-
-		// 解析播放列表标题和数量
-		if match := playlistTitleRegex.FindStringSubmatch(line); len(match) > 2 {
-			if total, err := strconv.Atoi(match[2]); err == nil {
-				currentDownload.Progress.TotalCount = total
-				currentDownload.Progress.PlaylistTitle = match[1]
-				currentDownload.Progress.Status = fmt.Sprintf("播放列表: %s (%d项)", match[1], total)
-				currentDownload.Progress.Phase = "extracting"
-			}
-		}
-
-		// 解析提取信息
-		if match := extractingRegex.FindStringSubmatch(line); len(match) > 1 {
-			currentDownload.Progress.Status = "正在提取视频信息..."
-			currentDownload.Progress.LastActivity = fmt.Sprintf("提取: %s", match[1])
-			currentDownload.Progress.Phase = "extracting"
-		}
-
-		// 解析提取器信息
-		if match := extractorRegex.FindStringSubmatch(line); len(match) > 3 {
-			currentDownload.Progress.LastActivity = fmt.Sprintf("[%s] %s: %s", match[1], match[2], match[3])
-			currentDownload.Progress.Phase = "extracting"
-		}
-
-		// 解析播放列表信息
-		if match := playlistRegex.FindStringSubmatch(line); len(match) > 1 {
-			if total, err := strconv.Atoi(match[1]); err == nil {
-				currentDownload.Progress.TotalCount = total
-				currentDownload.Progress.Status = fmt.Sprintf("发现播放列表，共%d个项目", total)
-			}
-		}
-
-		// 解析已完成的文件 - 多种检测方式（避免重复）
-		var completedFileName string
-		var detectionMethod string
-
-		// 方式1: 检测 "has already been downloaded" (最可靠)
-		if match := downloadingRegex.FindStringSubmatch(line); len(match) > 1 {
-			completedFileName = match[1]
-			detectionMethod = "already_downloaded"
-
-			// 对于已下载的文件，设置特殊的状态和速度，并标记为跳过状态
-			currentDownload.Progress.Speed = "已跳过"
-			currentDownload.Progress.Status = fmt.Sprintf("跳过已下载文件: %s", filepath.Base(match[1]))
-			currentDownload.Progress.Phase = "skipped" // 添加跳过状态标记
-		}
-
-		// 方式2: 检测合并完成
-		if completedFileName == "" {
-			if match := mergeCompleteRegex.FindStringSubmatch(line); len(match) > 1 {
-				completedFileName = match[1]
-				detectionMethod = "merge_complete"
-			}
-		}
-
-		// 方式3: 检测ffmpeg合并完成
-		if completedFileName == "" {
-			if match := ffmpegCompleteRegex.FindStringSubmatch(line); len(match) > 1 {
-				completedFileName = match[1]
-				detectionMethod = "ffmpeg_complete"
-			}
-		}
-
-		// 方式4: 已移除100%完成状态检测，避免与already_downloaded重复
-		// 注意：100%进度检测已移至下方的独立进度更新逻辑中
-
-		// 方式5: 检测播放列表完成时，只处理停止逻辑，不重复添加文件
-		if strings.Contains(line, "[download] Finished downloading playlist") {
-			// 标记下载完成
-			currentDownload.Progress.Progress = 100.0
-			currentDownload.Progress.Status = "下载完成"
-			currentDownload.Progress.Phase = "completed"
-			currentDownload.Progress.IsDownloading = false
-			currentDownload.IsRunning = false
-			fmt.Println("播放列表下载完成，自动停止下载任务")
-			// 不在这里添加文件，避免重复
-		}
-
-		// 处理完成的文件名
-		if completedFileName != "" {
-			// 提取文件名（去掉路径）
-			fileName := completedFileName
-			if idx := strings.LastIndex(fileName, "\\"); idx != -1 {
-				fileName = fileName[idx+1:]
-			}
-			if idx := strings.LastIndex(fileName, "/"); idx != -1 {
-				fileName = fileName[idx+1:]
-			}
-
-			// 清理文件名，确保UTF-8编码正确
-			fileName = cleanUTF8String(fileName)
-
-			// 只处理音频文件，过滤掉图片文件
-			if isAudioFile(fileName) {
-				// 更严格的重复检测：检查文件名是否已存在
-				isDuplicate := false
-				for _, existingFile := range currentDownload.Progress.CompletedFiles {
-					// 清理现有文件名并进行比较
-					cleanExistingFile := cleanUTF8String(existingFile)
-					if strings.EqualFold(cleanExistingFile, fileName) { // 不区分大小写比较
-						isDuplicate = true
-						break
-					}
-				}
-
-				if !isDuplicate {
-					currentDownload.Progress.CompletedFiles = append(currentDownload.Progress.CompletedFiles, fileName)
-					// 只保留最近3个
-					if len(currentDownload.Progress.CompletedFiles) > 3 {
-						currentDownload.Progress.CompletedFiles = currentDownload.Progress.CompletedFiles[len(currentDownload.Progress.CompletedFiles)-3:]
-					}
-					fmt.Printf("添加完成音频文件: %s (方法: %s, 总数: %d)\n", fileName, detectionMethod, len(currentDownload.Progress.CompletedFiles))
-				} else {
-					fmt.Printf("跳过重复文件: %s (方法: %s)\n", fileName, detectionMethod)
-				}
-			} else {
-				fmt.Printf("跳过非音频文件: %s (方法: %s)\n", fileName, detectionMethod)
-			}
-		}
-
-		// 解析完成进度 - 检测100%完成状态
-		if match := completedRegex.FindStringSubmatch(line); len(match) > 1 {
-			fileProgress := 100.0 // 因为正则表达式已经匹配了100.0%
-
-			// 检查是否是已跳过文件的100%输出（避免覆盖"已跳过"状态）
-			isSkippedFile := currentDownload.Progress.Phase == "skipped"
-
-			// 计算整体进度
-			var overallProgress float64
-			if currentDownload.Progress.TotalCount > 0 && currentDownload.Progress.CurrentIndex > 0 {
-				// 播放列表进度计算：(已完成项目数 + 当前项目进度) / 总项目数 * 100
-				completedItems := float64(currentDownload.Progress.CurrentIndex - 1) // 已完成的项目数
-				currentItemProgress := fileProgress / 100.0                          // 当前项目进度 (0-1)
-				overallProgress = (completedItems + currentItemProgress) / float64(currentDownload.Progress.TotalCount) * 100.0
-			} else {
-				// 单个文件下载，直接使用文件进度
-				overallProgress = fileProgress
-			}
-
-			currentDownload.Progress.Progress = overallProgress
-			currentDownload.Progress.FileProgress = fileProgress // 保存单个文件进度
-
-			// 只有不是跳过文件时才更新状态和速度
-			if !isSkippedFile {
-				currentDownload.Progress.Status = "文件下载完成"
-				currentDownload.Progress.Phase = "downloading"
-			}
-
-			// 当文件进度达到100%时，添加当前文件到已完成列表
-			if currentDownload.Progress.CurrentFile != "" {
-				fileName := currentDownload.Progress.CurrentFile
-				// 提取文件名（去掉路径）
-				if idx := strings.LastIndex(fileName, "\\"); idx != -1 {
-					fileName = fileName[idx+1:]
-				}
-				if idx := strings.LastIndex(fileName, "/"); idx != -1 {
-					fileName = fileName[idx+1:]
-				}
-
-				// 清理文件名，确保UTF-8编码正确
-				fileName = cleanUTF8String(fileName)
-
-				// 只处理音频文件
-				if isAudioFile(fileName) {
-					// 更严格的重复检测：检查文件名是否已存在
-					isDuplicate := false
-					for _, existingFile := range currentDownload.Progress.CompletedFiles {
-						// 清理现有文件名并进行比较
-						cleanExistingFile := cleanUTF8String(existingFile)
-						if strings.EqualFold(cleanExistingFile, fileName) { // 不区分大小写比较
-							isDuplicate = true
-							break
-						}
-					}
-
-					if !isDuplicate {
-						currentDownload.Progress.CompletedFiles = append(currentDownload.Progress.CompletedFiles, fileName)
-						// 只保留最近3个
-						if len(currentDownload.Progress.CompletedFiles) > 3 {
-							currentDownload.Progress.CompletedFiles = currentDownload.Progress.CompletedFiles[len(currentDownload.Progress.CompletedFiles)-3:]
-						}
-						fmt.Printf("添加完成音频文件: %s (方法: 100%%_progress, 总数: %d)\n", fileName, len(currentDownload.Progress.CompletedFiles))
-					} else {
-						fmt.Printf("跳过重复文件: %s (方法: 100%%_progress)\n", fileName)
-					}
-				}
-			}
-		}
-
-		// 解析下载完成
-		if match := finishedRegex.FindStringSubmatch(line); len(match) > 0 {
-			currentDownload.Progress.Progress = 100.0
-			currentDownload.Progress.Status = "下载完成"
-			currentDownload.Progress.Phase = "completed"
-		}
-
-		// 解析合并信息
-		if match := mergeRegex.FindStringSubmatch(line); len(match) > 1 {
-			currentDownload.Progress.Status = fmt.Sprintf("正在合并: %s", filepath.Base(match[1]))
-			currentDownload.Progress.Phase = "merging"
-		}
-
-		// 解析目标文件路径
-		if match := destinationRegex.FindStringSubmatch(line); len(match) > 1 {
-			currentDownload.Progress.CurrentFile = match[1]
-			fileName := filepath.Base(match[1])
-			currentDownload.Progress.CurrentTitle = fileName // 更新当前标题为文件名
-			currentDownload.Progress.Status = fmt.Sprintf("正在下载: %s", fileName)
-			fmt.Printf("设置当前文件: %s\n", match[1])
-		}
-
-		// 解析文件信息
-		if match := fileInfoRegex.FindStringSubmatch(line); len(match) > 1 {
-			currentDownload.Progress.CurrentTitle = match[1]
-			// 从当前项目索引构建文件名
-			if currentDownload.Progress.CurrentIndex > 0 && currentDownload.Progress.PlaylistTitle != "" {
-				// 构建预期的文件名格式
-				expectedFileName := fmt.Sprintf("%s p%02d", currentDownload.Progress.PlaylistTitle, currentDownload.Progress.CurrentIndex)
-				currentDownload.Progress.CurrentFile = expectedFileName + ".m4a"
-				fmt.Printf("根据项目索引设置当前文件: %s\n", currentDownload.Progress.CurrentFile)
-			} else {
-				currentDownload.Progress.CurrentFile = match[1]
-			}
-			currentDownload.Progress.Status = fmt.Sprintf("准备下载: %s", match[1])
-		}
-
-		// 解析标题信息
-		if match := titleRegex.FindStringSubmatch(line); len(match) > 2 {
-			currentDownload.Progress.CurrentTitle = match[1]
-			currentDownload.Progress.LastActivity = fmt.Sprintf("标题: %s - %s", match[1], match[2])
-		}
-
-		// 解析错误信息
-		if match := errorRegex.FindStringSubmatch(line); len(match) > 1 {
-			currentDownload.Progress.Status = fmt.Sprintf("错误: %s", match[1])
-			currentDownload.Progress.ErrorMessage = match[1]
-			currentDownload.Progress.LastActivity = fmt.Sprintf("ERROR: %s", match[1])
-			currentDownload.Progress.Phase = "error"
-		}
-
-		// 解析警告信息
-		if match := warningRegex.FindStringSubmatch(line); len(match) > 1 {
-			currentDownload.Progress.WarningMessage = match[1]
-			currentDownload.Progress.LastActivity = fmt.Sprintf("WARNING: %s", match[1])
+			downloadMutex.RUnlock()
 		}
+	}
+}
 
-		// 解析元数据信息
-		if match := metadataRegex.FindStringSubmatch(line); len(match) > 2 {
-			key := strings.ToLower(match[1])
-			value := match[2]
-
-			switch key {
-			case "duration":
-				if match := durationRegex.FindStringSubmatch(value); len(match) > 1 {
-					currentDownload.Progress.Duration = match[1]
-					currentDownload.Progress.LastActivity = fmt.Sprintf("时长: %s", match[1])
-				}
-			case "uploader":
-				if match := uploaderRegex.FindStringSubmatch(value); len(match) > 1 {
-					currentDownload.Progress.Uploader = match[1]
-					currentDownload.Progress.LastActivity = fmt.Sprintf("上传者: %s", match[1])
-				}
-			case "view_count":
-				if match := viewCountRegex.FindStringSubmatch(value); len(match) > 1 {
-					currentDownload.Progress.ViewCount = match[1]
-					currentDownload.Progress.LastActivity = fmt.Sprintf("播放量: %s", match[1])
-				}
+// 获取当前任务：优先返回正在下载的任务，其次是暂停的任务，最后是最近创建的任务
+// 调用方需持有 downloadMutex
+func currentTaskLocked() *DownloadTask {
+	for _, state := range []string{TaskStateRunning, TaskStatePaused, TaskStateQueued} {
+		for _, task := range downloadTasks {
+			if task.State == state {
+				return task
 			}
 		}
-
-		// 更新最后活动（如果没有其他更具体的信息）
-		if currentDownload.Progress.LastActivity == "" ||
-			!strings.Contains(currentDownload.Progress.LastActivity, "ERROR") &&
-				!strings.Contains(currentDownload.Progress.LastActivity, "WARNING") &&
-				!strings.Contains(currentDownload.Progress.LastActivity, "时长") &&
-				!strings.Contains(currentDownload.Progress.LastActivity, "上传者") &&
-				!strings.Contains(currentDownload.Progress.LastActivity, "播放量") {
-			currentDownload.Progress.LastActivity = line
-		}
-
-		downloadMutex.Unlock()
-
-		// 广播进度
-		broadcastProgress()
 	}
+	if len(downloadTasks) > 0 {
+		return downloadTasks[len(downloadTasks)-1]
+	}
+	return nil
+}
 
-	fmt.Printf("[%s] 输出解析结束，共处理%d行\n", source, lineCount)
+// 获取当前任务
+func getCurrentTask() *DownloadTask {
+	downloadMutex.RLock()
+	defer downloadMutex.RUnlock()
+	return currentTaskLocked()
 }
 
-// 监控下载状态
-func monitorDownload(ctx context.Context) {
-	ticker := time.NewTicker(10 * time.Second)
-	defer ticker.Stop()
+// 根据ID查找任务
+func findTask(id string) *DownloadTask {
+	downloadMutex.RLock()
+	defer downloadMutex.RUnlock()
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			downloadMutex.RLock()
-			if currentDownload != nil && currentDownload.IsRunning {
-				progress := currentDownload.Progress
-				if progress != nil {
-					fmt.Printf("=== 下载状态 ===\n")
-					fmt.Printf("状态: %s\n", progress.Status)
-					fmt.Printf("进度: %.1f%%\n", progress.Progress)
-					fmt.Printf("速度: %s\n", progress.Speed)
-					fmt.Printf("项目: %d/%d\n", progress.CurrentIndex, progress.TotalCount)
-					fmt.Printf("===============\n")
-				}
-			}
-			downloadMutex.RUnlock()
+	for _, task := range downloadTasks {
+		if task.ID == id {
+			return task
 		}
 	}
+	return nil
 }
 
-// 获取当前下载进度
-func getCurrentProgress() *DownloadProgress {
+// 获取任务进度的副本
+func getTaskProgress(task *DownloadTask) *DownloadProgress {
 	downloadMutex.RLock()
 	defer downloadMutex.RUnlock()
 
-	if currentDownload == nil {
+	if task == nil || task.Progress == nil {
 		return &DownloadProgress{IsDownloading: false}
 	}
 
-	// 返回进度的副本
-	progress := *currentDownload.Progress
+	progress := *task.Progress
+	progress.CompletedFiles = slices.Clone(task.Progress.CompletedFiles)
 	return &progress
 }
 
-// 停止当前下载
-func stopCurrentDownload() {
-	downloadMutex.Lock()
-	defer downloadMutex.Unlock()
+// 获取当前下载进度
+func getCurrentProgress() *DownloadProgress {
+	return getTaskProgress(getCurrentTask())
+}
 
-	if currentDownload != nil {
-		// 如果有正在运行的任务，先取消
-		if currentDownload.IsRunning {
-			if currentDownload.Cancel != nil {
-				currentDownload.Cancel()
-			}
+// 停止下载任务
+func stopTask(task *DownloadTask) bool {
+	downloadMutex.Lock()
 
-			// 如果有进程在运行，终止它
-			if currentDownload.Cmd != nil && currentDownload.Cmd.Process != nil {
-				fmt.Printf("停止下载，终止进程 PID: %d\n", currentDownload.Cmd.Process.Pid)
-				currentDownload.Cmd.Process.Kill()
-			}
+	switch task.State {
+	case TaskStateRunning, TaskStateQueued, TaskStatePaused:
+	default:
+		downloadMutex.Unlock()
+		return false
+	}
 
-			// 添加停止记录到历史
-			addTaskToHistory(currentDownload, "stopped", "用户手动停止")
+	// 正在运行或暂停后进程尚未退出时，取消运行并终止进程
+	if task.State == TaskStateRunning || task.runActive {
+		if task.Cancel != nil {
+			task.Cancel()
 		}
-
-		// 更新状态
-		currentDownload.IsRunning = false
-		if currentDownload.Progress != nil {
-			currentDownload.Progress.IsDownloading = false
-			currentDownload.Progress.IsPaused = false
-			currentDownload.Progress.Status = "下载已停止"
-			currentDownload.Progress.Phase = "stopped"
-			currentDownload.Progress.LastActivity = "用户停止下载"
+		if task.Cmd != nil && task.Cmd.Process != nil {
+			fmt.Printf("停止下载，终止进程 PID: %d\n", task.Cmd.Process.Pid)
+			task.Cmd.Process.Kill()
 		}
+	}
 
-		fmt.Println("下载任务已停止")
+	// 更新状态
+	setTaskStateLocked(task, TaskStateStopped)
+	task.IsRunning = false
+	task.ResumePending = false
+	finalizeItemsLocked(task, "用户手动停止")
+	task.Progress.IsDownloading = false
+	task.Progress.IsPaused = false
+	task.Progress.Status = "下载已停止"
+	task.Progress.Phase = "stopped"
+	task.Progress.LastActivity = "用户停止下载"
+	downloadMutex.Unlock()
 
-		// 广播状态更新
-		go broadcastProgress()
+	// 添加停止记录到历史
+	addTaskToHistory(task, "stopped", "用户手动停止")
 
-		// 延迟清空当前下载任务，给前端时间接收状态更新
-		go func() {
-			time.Sleep(2 * time.Second)
-			downloadMutex.Lock()
-			currentDownload = nil
-			downloadMutex.Unlock()
-			fmt.Println("下载任务已清理")
-		}()
+	fmt.Printf("下载任务 %s 已停止\n", task.ID)
+
+	// 广播状态更新
+	go broadcastProgress(task)
+	scheduleDownloads()
+	return true
+}
+
+// 停止当前下载
+func stopCurrentDownload() {
+	if task := getCurrentTask(); task != nil {
+		stopTask(task)
 	}
 }
 
-// 检查是否有正在进行的下载
+// 检查是否有正在进行或排队中的下载
 func hasActiveDownload() bool {
 	downloadMutex.RLock()
 	defer downloadMutex.RUnlock()
 
-	return currentDownload != nil && currentDownload.IsRunning
+	for _, task := range downloadTasks {
+		if task.State == TaskStateRunning || task.State == TaskStateQueued {
+			return true
+		}
+	}
+	return false
+}
+
+// 暂停下载任务 - 通过发送中断信号优雅地终止yt-dlp进程
+func pauseTask(task *DownloadTask) bool {
+	return pauseTaskWithReason(task, "")
 }
 
-// 暂停当前下载 - 通过发送中断信号优雅地终止yt-dlp进程
-func pauseCurrentDownload() bool {
+// 暂停下载任务，reason 不为空时作为错误信息显示（如磁盘空间不足）
+func pauseTaskWithReason(task *DownloadTask, reason string) bool {
 	downloadMutex.Lock()
-	defer downloadMutex.Unlock()
 
-	if currentDownload == nil || !currentDownload.IsRunning {
-		fmt.Println("没有正在进行的下载任务")
+	if task.State == TaskStatePaused {
+		downloadMutex.Unlock()
+		fmt.Println("下载已经暂停")
 		return false
 	}
 
-	if currentDownload.Progress.IsPaused {
-		fmt.Println("下载已经暂停")
+	if task.State != TaskStateRunning {
+		downloadMutex.Unlock()
+		fmt.Println("没有正在进行的下载任务")
 		return false
 	}
 
-	// 发送中断信号给yt-dlp进程
-	if currentDownload.Cmd != nil && currentDownload.Cmd.Process != nil {
-		fmt.Printf("暂停下载，发送中断信号给进程 PID: %d\n", currentDownload.Cmd.Process.Pid)
+	// 发送中断信号给yt-dlp进程；进程尚未启动（如等待重试）时直接标记暂停
+	if task.Cmd != nil && task.Cmd.Process != nil {
+		fmt.Printf("暂停下载，发送中断信号给进程 PID: %d\n", task.Cmd.Process.Pid)
 
 		// 在Windows上使用Kill，在Unix系统上使用Interrupt
+		var err error
 		if runtime.GOOS == "windows" {
-			err := currentDownload.Cmd.Process.Kill()
-			if err != nil {
-				fmt.Printf("暂停下载失败: %v\n", err)
-				return false
-			}
+			err = task.Cmd.Process.Kill()
 		} else {
-			err := currentDownload.Cmd.Process.Signal(os.Interrupt)
-			if err != nil {
-				fmt.Printf("暂停下载失败: %v\n", err)
-				return false
-			}
+			err = task.Cmd.Process.Signal(os.Interrupt)
 		}
+		if err != nil {
+			downloadMutex.Unlock()
+			fmt.Printf("暂停下载失败: %v\n", err)
+			return false
+		}
+	}
 
-		// 更新状态
-		currentDownload.Progress.IsPaused = true
-		currentDownload.Progress.IsDownloading = false
-		currentDownload.Progress.Status = "下载已暂停"
-		currentDownload.Progress.Phase = "paused"
-		currentDownload.Progress.LastActivity = "用户暂停下载"
-		currentDownload.IsRunning = false
-
-		fmt.Println("下载已暂停，可以使用继续功能恢复下载")
-		go broadcastProgress()
-		return true
+	// 更新状态
+	setTaskStateLocked(task, TaskStatePaused)
+	task.IsRunning = false
+	resetDownloadingItemsLocked(task)
+	task.Progress.IsPaused = true
+	task.Progress.IsDownloading = false
+	task.Progress.Status = "下载已暂停"
+	task.Progress.Phase = "paused"
+	task.Progress.LastActivity = "用户暂停下载"
+	if reason != "" {
+		task.Progress.Status = fmt.Sprintf("下载已暂停: %s", reason)
+		task.Progress.ErrorMessage = reason
+		task.Progress.LastActivity = reason
 	}
+	downloadMutex.Unlock()
 
-	fmt.Println("无法找到下载进程")
-	return false
-}
+	fmt.Println("下载已暂停，可以使用继续功能恢复下载")
+	go broadcastProgress(task)
 
-// 继续当前下载 - 使用--continue选项重新启动yt-dlp
-func resumeCurrentDownload() bool {
-	downloadMutex.Lock()
-	defer downloadMutex.Unlock()
+	// 暂停的任务释放下载名额
+	scheduleDownloads()
+	return true
+}
 
-	if currentDownload == nil {
-		fmt.Println("没有下载任务")
+// 暂停当前下载
+func pauseCurrentDownload() bool {
+	task := getCurrentTask()
+	if task == nil {
+		fmt.Println("没有正在进行的下载任务")
 		return false
 	}
+	return pauseTask(task)
+}
 
-	if !currentDownload.Progress.IsPaused {
+// 继续下载任务 - 重新排队，启动时使用--continue选项
+func resumeTask(task *DownloadTask) bool {
+	downloadMutex.Lock()
+
+	if task.State != TaskStatePaused {
+		downloadMutex.Unlock()
 		fmt.Println("下载未暂停")
 		return false
 	}
 
 	fmt.Println("继续下载，使用 --continue 选项")
 
-	// 重新构建命令，使用--continue选项
-	cmd := buildYtDlpCommand(currentDownload, true)
-	currentDownload.Cmd = cmd
-
-	// 设置环境变量
-	cmd.Env = append(os.Environ(),
-		"PYTHONIOENCODING=utf-8",
-		"LC_ALL=en_US.UTF-8",
-		"LANG=en_US.UTF-8",
-	)
+	setTaskStateLocked(task, TaskStateQueued)
+	task.ResumePending = true
+	task.windowPaused = false
+	task.Progress.IsPaused = false
+	task.Progress.ErrorMessage = ""
+	task.Progress.Status = "等待继续下载..."
+	task.Progress.Phase = "queued"
+	downloadMutex.Unlock()
 
-	fmt.Printf("继续执行命令: %s\n", formatCommand(cmd))
+	go broadcastProgress(task)
+	scheduleDownloads()
+	return true
+}
 
-	// 创建输出管道
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		fmt.Printf("创建stdout管道失败: %v\n", err)
+// 继续当前下载
+func resumeCurrentDownload() bool {
+	task := getCurrentTask()
+	if task == nil {
+		fmt.Println("没有下载任务")
 		return false
 	}
+	return resumeTask(task)
+}
 
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		fmt.Printf("创建stderr管道失败: %v\n", err)
-		return false
-	}
+// 重新执行已结束的任务
+func retryTask(task *DownloadTask) bool {
+	downloadMutex.Lock()
 
-	// 启动命令
-	if err := cmd.Start(); err != nil {
-		fmt.Printf("继续下载失败: %v\n", err)
+	switch task.State {
+	case TaskStateFailed, TaskStateStopped, TaskStateCompleted:
+	default:
+		downloadMutex.Unlock()
 		return false
 	}
 
-	fmt.Printf("yt-dlp已重新启动，PID: %d\n", cmd.Process.Pid)
-
-	// 更新状态
-	currentDownload.Progress.IsPaused = false
-	currentDownload.Progress.IsDownloading = true
-	currentDownload.Progress.Status = "继续下载中..."
-	currentDownload.Progress.Phase = "downloading"
-	currentDownload.Progress.LastActivity = "用户继续下载"
-	currentDownload.IsRunning = true
-
-	// 重新创建context
-	ctx, cancel := context.WithCancel(context.Background())
-	currentDownload.Cancel = cancel
-
-	// 启动输出解析goroutine
-	go parseOutput(stdout, "stdout")
-	go parseOutput(stderr, "stderr")
-
-	// 启动状态监控
-	go monitorDownload(ctx)
-
-	// 在新的goroutine中等待命令完成
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				fmt.Printf("下载goroutine panic: %v\n", r)
-			}
-		}()
-
-		// 等待命令完成
-		done := make(chan error, 1)
-		go func() {
-			defer func() {
-				if r := recover(); r != nil {
-					fmt.Printf("命令等待goroutine panic: %v\n", r)
-					done <- fmt.Errorf("命令执行异常: %v", r)
-				}
-			}()
-			done <- cmd.Wait()
-		}()
-
-		// 设置超时
-		timeout := time.After(10 * time.Minute)
-
-		select {
-		case err := <-done:
-			if err != nil {
-				fmt.Printf("yt-dlp进程结束: %v\n", err)
-				downloadMutex.Lock()
-				if currentDownload != nil && currentDownload.Progress != nil {
-					currentDownload.Progress.Status = "下载失败"
-					currentDownload.Progress.Phase = "error"
-					currentDownload.Progress.ErrorMessage = err.Error()
-					currentDownload.IsRunning = false
-				}
-				downloadMutex.Unlock()
-				broadcastProgress()
-			} else {
-				fmt.Println("yt-dlp进程正常结束")
-			}
-		case <-timeout:
-			fmt.Println("下载超时，终止进程")
-			if cmd.Process != nil {
-				cmd.Process.Kill()
-			}
-		case <-ctx.Done():
-			fmt.Println("下载被取消，终止进程")
-			if cmd.Process != nil {
-				cmd.Process.Kill()
-			}
-		}
-	}()
+	setTaskStateLocked(task, TaskStateQueued)
+	task.ResumePending = false
+	task.deadlineAt = time.Time{}
+	task.historyRun++
+	task.Items = nil
+	task.RetryItems = nil
+	task.Progress = &DownloadProgress{
+		TaskID:         task.ID,
+		TaskState:      TaskStateQueued,
+		Status:         "排队等待重试...",
+		LastActivity:   "任务已重新加入队列",
+		CompletedFiles: make([]string, 0),
+		Phase:          "queued",
+	}
+	downloadMutex.Unlock()
 
-	go broadcastProgress()
+	fmt.Printf("下载任务 %s 重新加入队列\n", task.ID)
+	go broadcastProgress(task)
+	scheduleDownloads()
 	return true
 }
 
-// 任务历史结构
-type TaskHistory struct {
-	ID        int    `json:"id"`
-	Title     string `json:"title"`
-	Status    string `json:"status"`
-	URL       string `json:"url"`
-	CreatedAt string `json:"created_at"`
-	FileSize  string `json:"file_size,omitempty"`
-	Duration  string `json:"duration,omitempty"`
-	Progress  int    `json:"progress,omitempty"`
-	Error     string `json:"error,omitempty"`
-}
-
-// 历史记录存储
-var (
-	taskHistoryMutex sync.RWMutex
-	taskHistoryList  []TaskHistory
-	nextTaskID       int = 1
-)
-
-// 初始化历史记录，扫描已存在的文件
-func initializeHistory() {
-	fmt.Println("初始化历史记录，扫描 audiobooks 目录...")
-
-	// 扫描 audiobooks 目录下的所有子目录
-	audiobooksDir := "audiobooks"
-	if _, err := os.Stat(audiobooksDir); os.IsNotExist(err) {
-		fmt.Printf("audiobooks 目录不存在: %s\n", audiobooksDir)
-		return
-	}
+// 删除任务，未结束的任务会先被停止
+func removeTask(task *DownloadTask) {
+	downloadMutex.RLock()
+	state := task.State
+	downloadMutex.RUnlock()
 
-	// 遍历 audiobooks 下的所有子目录
-	entries, err := os.ReadDir(audiobooksDir)
-	if err != nil {
-		fmt.Printf("读取 audiobooks 目录失败: %v\n", err)
-		return
+	if state == TaskStateRunning || state == TaskStateQueued || state == TaskStatePaused {
+		stopTask(task)
 	}
 
-	taskHistoryMutex.Lock()
-	defer taskHistoryMutex.Unlock()
-
-	for _, entry := range entries {
-		if entry.IsDir() {
-			subDir := filepath.Join(audiobooksDir, entry.Name())
-			audioFiles := scanExistingAudioFiles(subDir)
-
-			if len(audioFiles) > 0 {
-				// 为每个有音频文件的目录创建一个历史记录
-				historyItem := TaskHistory{
-					ID:        nextTaskID,
-					Title:     fmt.Sprintf("已完成：%s", entry.Name()),
-					Status:    "completed",
-					URL:       fmt.Sprintf("本地目录: %s", subDir),
-					CreatedAt: "2025/01/20 00:00:00", // 使用固定时间，表示历史文件
-					FileSize:  fmt.Sprintf("%d个文件", len(audioFiles)),
-					Progress:  100,
-				}
-
-				taskHistoryList = append(taskHistoryList, historyItem)
-				nextTaskID++
-
-				fmt.Printf("添加历史记录: %s (%d个音频文件)\n", entry.Name(), len(audioFiles))
-			}
+	downloadMutex.Lock()
+	for i, t := range downloadTasks {
+		if t == task {
+			downloadTasks = slices.Delete(downloadTasks, i, i+1)
+			break
 		}
 	}
+	downloadMutex.Unlock()
+	recordSubscriptionTask(task)
 
-	fmt.Printf("历史记录初始化完成，共发现 %d 个已完成的目录\n", len(taskHistoryList))
+	fmt.Printf("下载任务 %s 已删除\n", task.ID)
 }
 
-// 添加任务到历史记录
-func addTaskToHistory(task *DownloadTask, status string, errorMsg string) {
-	taskHistoryMutex.Lock()
-	defer taskHistoryMutex.Unlock()
-
-	// 获取任务标题
-	title := "未知任务"
-	if task.Progress != nil {
-		if task.Progress.PlaylistTitle != "" {
-			title = task.Progress.PlaylistTitle
-		} else if task.Progress.CurrentTitle != "" {
-			title = task.Progress.CurrentTitle
-		}
-	}
-
-	// 计算进度
-	progress := 0
-	if task.Progress != nil {
-		progress = int(task.Progress.Progress)
-	}
-
-	// 创建历史记录
-	historyItem := TaskHistory{
-		ID:        nextTaskID,
-		Title:     title,
-		Status:    status,
-		URL:       task.URL,
-		CreatedAt: time.Now().Format("2006-01-02 15:04:05"),
-		Progress:  progress,
-		Error:     errorMsg,
-	}
-
-	// 添加文件大小和时长信息
-	if task.Progress != nil {
-		historyItem.FileSize = task.Progress.FileSize
-		historyItem.Duration = task.Progress.Duration
+// 广播任务条目列表
+func broadcastItems(task *DownloadTask) {
+	message, err := json.Marshal(map[string]any{
+		"type":    "task_items",
+		"task_id": task.ID,
+		"data":    getTaskItems(task),
+	})
+	if err != nil {
+		fmt.Printf("序列化条目数据失败: %v\n", err)
+		return
 	}
 
-	// 添加到历史列表
-	taskHistoryList = append(taskHistoryList, historyItem)
-	nextTaskID++
+	wsConnMutex.Lock()
+	defer wsConnMutex.Unlock()
 
-	// 只保留最近50个记录
-	if len(taskHistoryList) > 50 {
-		taskHistoryList = taskHistoryList[len(taskHistoryList)-50:]
+	for i := len(wsConnections) - 1; i >= 0; i-- {
+		if err := wsConnections[i].WriteMessage(websocket.TextMessage, message); err != nil {
+			fmt.Printf("WebSocket连接[%d]发送失败: %v，移除连接\n", i, err)
+			wsConnections = slices.Delete(wsConnections, i, i+1)
+		}
 	}
-
-	fmt.Printf("添加任务到历史记录: ID=%d, Title=%s, Status=%s\n", historyItem.ID, historyItem.Title, historyItem.Status)
-}
-
-// 获取任务历史
-func getTaskHistory() []TaskHistory {
-	taskHistoryMutex.RLock()
-	defer taskHistoryMutex.RUnlock()
-
-	// 返回真实历史记录，按时间倒序排列
-	result := make([]TaskHistory, len(taskHistoryList))
-	copy(result, taskHistoryList)
-
-	// 简单的倒序排列
-	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
-		result[i], result[j] = result[j], result[i]
-	}
-
-	return result
 }
 
 // WebSocket连接管理
@@ -1350,33 +1454,48 @@ func removeWebSocketConnection(conn *websocket.Conn) {
 	}
 }
 
-// 广播进度更新
-func broadcastProgress() {
+// 广播任务进度更新
+// 每个任务发送 task_progress 消息；当前任务同时发送 progress 消息，兼容单任务界面
+func broadcastProgress(task *DownloadTask) {
+	if task == nil {
+		return
+	}
+
 	wsConnMutex.Lock()
 	defer wsConnMutex.Unlock()
 
-	progress := getCurrentProgress()
-	message, err := json.Marshal(map[string]any{
-		"type": "progress",
-		"data": progress,
-	})
-	if err != nil {
-		fmt.Printf("序列化进度数据失败: %v\n", err)
-		return
+	progress := getTaskProgress(task)
+	messages := make([][]byte, 0, 2)
+
+	types := []string{"task_progress"}
+	if getCurrentTask() == task {
+		types = append(types, "progress")
+	}
+	for _, msgType := range types {
+		message, err := json.Marshal(map[string]any{
+			"type":    msgType,
+			"task_id": task.ID,
+			"data":    progress,
+		})
+		if err != nil {
+			fmt.Printf("序列化进度数据失败: %v\n", err)
+			return
+		}
+		messages = append(messages, message)
 	}
 
-	fmt.Printf("广播进度更新: 连接数=%d, 状态=%+v\n", len(wsConnections), progress)
+	fmt.Printf("广播进度更新: 任务=%s, 连接数=%d, 状态=%+v\n", task.ID, len(wsConnections), progress)
 
 	// 使用倒序遍历，方便删除失效连接
 	for i := len(wsConnections) - 1; i >= 0; i-- {
 		conn := wsConnections[i]
-		err := conn.WriteMessage(websocket.TextMessage, message)
-		if err != nil {
-			fmt.Printf("WebSocket连接[%d]发送失败: %v，移除连接\n", i, err)
-			// 移除失效连接
-			wsConnections = slices.Delete(wsConnections, i, i+1)
-		} else {
-			fmt.Printf("WebSocket连接[%d]发送成功\n", i)
+		for _, message := range messages {
+			if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				fmt.Printf("WebSocket连接[%d]发送失败: %v，移除连接\n", i, err)
+				// 移除失效连接
+				wsConnections = slices.Delete(wsConnections, i, i+1)
+				break
+			}
 		}
 	}
 }
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestItemRetryBackoff(t *testing.T) {
+	tests := []struct {
+		delay   time.Duration
+		attempt int
+		want    time.Duration
+	}{
+		{30 * time.Second, 1, 30 * time.Second},
+		{30 * time.Second, 2, time.Minute},
+		{30 * time.Second, 4, 4 * time.Minute},
+		{30 * time.Second, 7, 30 * time.Minute},
+		{30 * time.Second, 100, 30 * time.Minute},
+		{time.Second, 1000, 30 * time.Minute},
+		{time.Hour, 1, 30 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := itemRetryBackoff(tt.delay, tt.attempt); got != tt.want {
+			t.Errorf("itemRetryBackoff(%v, %d) = %v, want %v", tt.delay, tt.attempt, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// 收藏夹接口每页最多返回的条目数
+const favoritePageSize = 20
+
+// 收藏夹
+type FavoriteFolder struct {
+	ID         int64  `json:"id"`
+	Title      string `json:"title"`
+	MediaCount int    `json:"media_count"`
+	URL        string `json:"url"` // 作为下载链接使用
+}
+
+// 收藏夹内容（分页）
+type FavoriteFolderContents struct {
+	Folder  FavoriteFolder `json:"folder"`
+	Entries []VideoInfo    `json:"entries"`
+	Page    int            `json:"page"`
+	HasMore bool           `json:"has_more"`
+}
+
+// 稍后再看列表
+type WatchLaterList struct {
+	Title   string      `json:"title"`
+	URL     string      `json:"url"` // 作为下载链接使用
+	Total   int         `json:"total"`
+	Entries []VideoInfo `json:"entries"`
+}
+
+// 收藏夹的下载链接
+func favoriteFolderURL(id int64) string {
+	return fmt.Sprintf("https://www.bilibili.com/medialist/detail/ml%d", id)
+}
+
+// 稍后再看的下载链接
+const watchLaterURL = "https://www.bilibili.com/list/watchlater"
+
+// 使用已保存的登录信息请求哔哩哔哩接口，并将 data 字段（番剧接口为 result 字段）解析到 data
+func getBilibiliAPI(apiURL string, data interface{}) error {
+	client := newHTTPClient(ProxyPurposeBilibili, 10*time.Second)
+
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		return err
+	}
+
+	// 设置cookies，未登录时部分接口仍可访问
+	if hasCookies() {
+		if err := setCookiesFromFile(req); err != nil {
+			return fmt.Errorf("读取登录信息失败: %v", err)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	var apiResp struct {
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+		Result  json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return fmt.Errorf("解析响应失败: %v", err)
+	}
+	if apiResp.Code == -101 {
+		return fmt.Errorf("用户未登录或cookies无效")
+	}
+	if apiResp.Code != 0 {
+		return fmt.Errorf("接口返回错误 %d: %s", apiResp.Code, apiResp.Message)
+	}
+
+	payload := apiResp.Data
+	if len(payload) == 0 {
+		payload = apiResp.Result
+	}
+	if err := json.Unmarshal(payload, data); err != nil {
+		return fmt.Errorf("解析响应失败: %v", err)
+	}
+	return nil
+}
+
+// 获取当前登录用户的 mid
+func getLoginMid() (int64, error) {
+	var nav struct {
+		IsLogin bool  `json:"isLogin"`
+		Mid     int64 `json:"mid"`
+	}
+	if err := getBilibiliAPI("https://api.bilibili.com/x/web-interface/nav", &nav); err != nil {
+		return 0, err
+	}
+	if !nav.IsLogin || nav.Mid == 0 {
+		return 0, fmt.Errorf("用户未登录或cookies无效")
+	}
+	return nav.Mid, nil
+}
+
+// 获取当前登录用户创建的收藏夹
+func getFavoriteFolders() ([]FavoriteFolder, error) {
+	mid, err := getLoginMid()
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		List []struct {
+			ID         int64  `json:"id"`
+			Title      string `json:"title"`
+			MediaCount int    `json:"media_count"`
+		} `json:"list"`
+	}
+	apiURL := fmt.Sprintf("https://api.bilibili.com/x/v3/fav/folder/created/list-all?up_mid=%d", mid)
+	if err := getBilibiliAPI(apiURL, &data); err != nil {
+		return nil, err
+	}
+
+	folders := make([]FavoriteFolder, 0, len(data.List))
+	for _, folder := range data.List {
+		folders = append(folders, FavoriteFolder{
+			ID:         folder.ID,
+			Title:      folder.Title,
+			MediaCount: folder.MediaCount,
+			URL:        favoriteFolderURL(folder.ID),
+		})
+	}
+	return folders, nil
+}
+
+// 获取收藏夹内容，page 从1开始
+func getFavoriteFolderContents(id int64, page int) (*FavoriteFolderContents, error) {
+	var data struct {
+		Info struct {
+			ID         int64  `json:"id"`
+			Title      string `json:"title"`
+			MediaCount int    `json:"media_count"`
+		} `json:"info"`
+		Medias []struct {
+			Title    string `json:"title"`
+			Cover    string `json:"cover"`
+			Duration int    `json:"duration"`
+			BVID     string `json:"bvid"`
+			PubTime  int64  `json:"pubtime"`
+		} `json:"medias"`
+		HasMore bool `json:"has_more"`
+	}
+	apiURL := fmt.Sprintf("https://api.bilibili.com/x/v3/fav/resource/list?media_id=%d&pn=%d&ps=%d&platform=web", id, page, favoritePageSize)
+	if err := getBilibiliAPI(apiURL, &data); err != nil {
+		return nil, err
+	}
+
+	contents := &FavoriteFolderContents{
+		Folder: FavoriteFolder{
+			ID:         data.Info.ID,
+			Title:      data.Info.Title,
+			MediaCount: data.Info.MediaCount,
+			URL:        favoriteFolderURL(id),
+		},
+		Entries: make([]VideoInfo, 0, len(data.Medias)),
+		Page:    page,
+		HasMore: data.HasMore,
+	}
+
+	// 序号与下载时的播放列表序号一致，可直接用于条目选择
+	for i, media := range data.Medias {
+		contents.Entries = append(contents.Entries, videoInfoFromAPI((page-1)*favoritePageSize+i+1,
+			media.BVID, media.Title, media.Cover, media.Duration, media.PubTime))
+	}
+	return contents, nil
+}
+
+// 获取稍后再看列表
+func getWatchLaterList() (*WatchLaterList, error) {
+	var data struct {
+		Count int `json:"count"`
+		List  []struct {
+			BVID     string `json:"bvid"`
+			Title    string `json:"title"`
+			Pic      string `json:"pic"`
+			Duration int    `json:"duration"`
+			PubDate  int64  `json:"pubdate"`
+		} `json:"list"`
+	}
+	if err := getBilibiliAPI("https://api.bilibili.com/x/v2/history/toview", &data); err != nil {
+		return nil, err
+	}
+
+	list := &WatchLaterList{
+		Title:   "稍后再看",
+		URL:     watchLaterURL,
+		Total:   data.Count,
+		Entries: make([]VideoInfo, 0, len(data.List)),
+	}
+	for i, item := range data.List {
+		list.Entries = append(list.Entries, videoInfoFromAPI(i+1, item.BVID, item.Title, item.Pic, item.Duration, item.PubDate))
+	}
+	return list, nil
+}
+
+// 根据接口返回的视频信息构建 VideoInfo
+func videoInfoFromAPI(index int, bvid, title, cover string, duration int, pubTime int64) VideoInfo {
+	info := VideoInfo{
+		Index:           index,
+		ID:              bvid,
+		URL:             fmt.Sprintf("https://www.bilibili.com/video/%s/", bvid),
+		Title:           title,
+		Duration:        formatDuration(float64(duration)),
+		DurationSeconds: float64(duration),
+		Thumbnail:       cover,
+	}
+	if pubTime > 0 {
+		info.UploadDate = time.Unix(pubTime, 0).Format("20060102")
+	}
+	return info
+}
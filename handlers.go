@@ -1,11 +1,17 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/gorilla/websocket"
 	"github.com/skip2/go-qrcode"
 )
@@ -38,11 +44,36 @@ type DownloadRequest struct {
 	Quality        string `json:"quality,omitempty"`
 	RetryCount     int    `json:"retry_count,omitempty"`
 	WriteThumbnail bool   `json:"write_thumbnail,omitempty"`
+
+	MaxDurationMinutes int  `json:"max_duration_minutes,omitempty"` // 任务总时限（分钟），0 表示不限
+	IgnoreArchive      bool `json:"ignore_archive,omitempty"`       // 忽略下载存档，重新下载已下载过的视频
+	IgnoreSpaceCheck   bool `json:"ignore_space_check,omitempty"`   // 预估大小超过剩余空间时仍然下载
+	SegmentMinutes     int  `json:"segment_minutes,omitempty"`      // 直播录制按此时长（分钟）分段保存，0 表示不分段
+	BandwidthLimitKBps int  `json:"bandwidth_limit_kbps,omitempty"` // 任务限速（KB/s），0 表示使用配置中的默认值
+
+	// 计划开始时间，格式 "YYYY-MM-DD HH:MM"，为空表示立即开始
+	StartAt string `json:"start_at,omitempty"`
+
+	// 只下载选中的条目：序号、序号范围或视频ID，以逗号分隔，如 "40-80,BV1xx411c7mD"
+	Items string `json:"items,omitempty"`
+
+	// 条目筛选，主要用于UP主全部投稿：标题关键字和上传日期范围（YYYY-MM-DD，含首尾）
+	TitleKeyword string `json:"title_keyword,omitempty"`
+	DateAfter    string `json:"date_after,omitempty"`
+	DateBefore   string `json:"date_before,omitempty"`
 }
 
 // 预检查请求
 type PreCheckRequest struct {
-	URL string `json:"url"`
+	URL      string `json:"url"`
+	SavePath string `json:"save_path,omitempty"` // 用于检查剩余空间
+	Quality  string `json:"quality,omitempty"`   // 用于估算文件大小
+	Items    string `json:"items,omitempty"`     // 条目选择，格式同 DownloadRequest.Items
+
+	// 条目筛选，格式同 DownloadRequest
+	TitleKeyword string `json:"title_keyword,omitempty"`
+	DateAfter    string `json:"date_after,omitempty"`
+	DateBefore   string `json:"date_before,omitempty"`
 }
 
 // 预检查响应
@@ -54,24 +85,70 @@ type PreCheckResponse struct {
 	AudioCount int         `json:"audio_count"`
 	IsPlaylist bool        `json:"is_playlist"`
 	Entries    []VideoInfo `json:"entries,omitempty"`
+
+	// 选中条目的汇总信息
+	SelectedCount        int     `json:"selected_count"`
+	TotalDuration        string  `json:"total_duration"`
+	TotalDurationSeconds float64 `json:"total_duration_seconds"`
+	EstimatedSize        int64   `json:"estimated_size"`                // 预估下载大小（字节）
+	EstimatedSizeText    string  `json:"estimated_size_text,omitempty"` // 格式化后的预估大小
+	UnknownSizeCount     int     `json:"unknown_size_count,omitempty"`  // 无法获取大小、按平均值估算的条目数
+	FreeSpace            int64   `json:"free_space,omitempty"`          // 目标目录剩余空间（字节）
+	SpaceWarning         string  `json:"space_warning,omitempty"`
+	VipOnlyCount         int     `json:"vip_only_count,omitempty"` // 选中条目中大会员专享的剧集数
+	VipWarning           string  `json:"vip_warning,omitempty"`    // 当前账号没有大会员时的提示
+	IsLive               bool    `json:"is_live,omitempty"`        // 直播间是否正在直播
 }
 
 // 视频信息
 type VideoInfo struct {
-	Title     string `json:"title"`
-	Duration  string `json:"duration"`
-	Thumbnail string `json:"thumbnail"`
+	Index           int     `json:"index,omitempty"`
+	ID              string  `json:"id,omitempty"`
+	URL             string  `json:"url,omitempty"`
+	Title           string  `json:"title"`
+	Duration        string  `json:"duration"`
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+	Thumbnail       string  `json:"thumbnail"`
+	Filesize        int64   `json:"filesize,omitempty"`    // 所选画质的文件大小（字节），可能为估算值
+	UploadDate      string  `json:"upload_date,omitempty"` // 上传日期，格式 YYYYMMDD
+	Episode         string  `json:"episode,omitempty"`     // 番剧集数
+	VipOnly         bool    `json:"vip_only,omitempty"`    // 番剧大会员专享
+
+	untitled bool // 没有标题，Title 为显示用的“第N集”
 }
 
 // DownloadProgress 已在 download.go 中定义
 
 // 配置结构
 type Config struct {
-	SavePath       string `json:"save_path"`
-	TitleRegex     string `json:"title_regex"`
-	Quality        string `json:"quality"`
-	RetryCount     int    `json:"retry_count"`
-	WriteThumbnail bool   `json:"write_thumbnail"`
+	SavePath               string `json:"save_path"`
+	TitleRegex             string `json:"title_regex"`
+	Quality                string `json:"quality"`
+	RetryCount             int    `json:"retry_count"`
+	WriteThumbnail         bool   `json:"write_thumbnail"`
+	MaxConcurrentDownloads int    `json:"max_concurrent_downloads"` // 同时进行的下载任务数
+	HistoryMaxEntries      int    `json:"history_max_entries"`      // 历史记录最多保留条数
+	HistoryMaxDays         int    `json:"history_max_days"`         // 历史记录保留天数，0 表示不限
+	StallTimeoutSeconds    int    `json:"stall_timeout_seconds"`    // 无进展多少秒后终止下载
+	ItemRetryAttempts      int    `json:"item_retry_attempts"`      // 失败条目自动重试次数，0 表示不重试
+	ItemRetryDelaySeconds  int    `json:"item_retry_delay_seconds"` // 首次重试间隔（秒），之后每次翻倍
+	DownloadArchive        bool   `json:"download_archive"`         // 按视频ID记录已下载内容，跨任务去重
+	MinFreeSpaceMB         int    `json:"min_free_space_mb"`        // 磁盘最少保留空间（MB），低于时暂停下载，0 表示不检查
+
+	// 音频库配额：子目录 -> 最大占用空间（MB），达到配额时暂停下载
+	LibraryQuotas map[string]int `json:"library_quotas,omitempty"`
+
+	// 限速（KB/s），0 表示不限速；全局限速按正在下载的任务数平均分配
+	BandwidthLimitKBps     int `json:"bandwidth_limit_kbps"`
+	TaskBandwidthLimitKBps int `json:"task_bandwidth_limit_kbps"` // 任务默认限速，可在创建任务时单独设置
+
+	// 代理地址，如 "http://127.0.0.1:7890" 或 "socks5://127.0.0.1:1080"，为空时使用环境变量中的代理设置
+	Proxy string `json:"proxy"`
+	// 按用途覆盖代理：bilibili、github、ytdlp，设置为 "direct" 表示直接连接
+	ProxyOverrides map[string]string `json:"proxy_overrides,omitempty"`
+
+	// 允许下载的时段，如 ["23:00-07:00"]，为空表示不限；时段结束时暂停下载，开始时自动继续
+	DownloadWindows []string `json:"download_windows,omitempty"`
 }
 
 // 生成二维码
@@ -201,87 +278,132 @@ func preCheckVideo(c *gin.Context) {
 		return
 	}
 
+	selection, err := parseItemSelection(req.Items)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "条目选择无效: " + err.Error()})
+		return
+	}
+	if !selection.IsEmpty() {
+		parsedURL = stripPartSelector(parsedURL)
+	}
+
+	filter, err := parseCatalogFilter(req.TitleKeyword, req.DateAfter, req.DateBefore)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "筛选条件无效: " + err.Error()})
+		return
+	}
+
 	// 获取视频信息
-	info, err := getVideoInfo(parsedURL)
+	quality := resolveQuality(req.Quality)
+	info, err := getVideoInfo(parsedURL, quality, selection, filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取视频信息失败: " + err.Error()})
 		return
 	}
 
+	// 记录预估大小，开始下载时用于检查剩余空间
+	saveSizeEstimate(sizeEstimateKey(parsedURL, quality, selection, filter), info.EstimatedSize)
+	if free, err := getFreeSpace(filepath.Join("audiobooks", req.SavePath)); err == nil {
+		info.FreeSpace = int64(free)
+		if info.EstimatedSize > info.FreeSpace {
+			info.SpaceWarning = fmt.Sprintf("预估大小 %s 超过剩余空间 %s", formatFileSize(info.EstimatedSize), formatFileSize(info.FreeSpace))
+		}
+	}
+
 	c.JSON(http.StatusOK, info)
 }
 
 // 开始下载 (HTTP处理器)
 func startDownloadHandler(c *gin.Context) {
-	// 检查是否已有正在进行的下载
-	if hasActiveDownload() {
-		c.JSON(http.StatusConflict, gin.H{"error": "已有下载任务正在进行中，请等待完成或停止当前任务"})
-		return
-	}
-
 	var req DownloadRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
 		return
 	}
 
-	// 解析链接
-	parsedURL, err := parseURL(req.URL)
+	// 创建下载任务并加入队列
+	task, err := newDownloadTask(req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "链接解析失败: " + err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 创建下载任务
-	task := &DownloadTask{
-		URL:            parsedURL,
-		SavePath:       req.SavePath,
-		TitleRegex:     req.TitleRegex,
-		Quality:        req.Quality,
-		RetryCount:     req.RetryCount,
-		WriteThumbnail: req.WriteThumbnail,
+	// 直播录制无法使用 ffmpeg 不支持的代理
+	if task.Type == TaskTypeLive {
+		if _, err := liveRecorderProxy(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 	}
 
-	// 启动下载任务
-	go func() {
-		// 添加任务开始记录
-		addTaskToHistory(task, "downloading", "")
+	// 番剧单集需要大会员而当前账号没有时直接拒绝
+	if err := checkBangumiAccess(task.URL); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 
-		if err := startDownload(task); err != nil {
-			fmt.Printf("下载失败: %v\n", err)
-			// 添加失败记录
-			addTaskToHistory(task, "failed", err.Error())
+	// 预估大小超过剩余空间时拒绝任务，除非明确要求忽略
+	// 没有预检查记录时不在请求中估算（大型合集需要数分钟），改为开始下载前检查
+	warning, estimated := checkEstimatedSpace(task, false)
+	if warning != "" && !req.IgnoreSpaceCheck {
+		c.JSON(http.StatusInsufficientStorage, gin.H{"error": warning})
+		return
+	}
+	if estimated <= 0 && task.Type != TaskTypeLive {
+		if req.IgnoreSpaceCheck {
+			warning = "没有预检查记录，未检查剩余空间"
 		} else {
-			fmt.Println("下载成功完成")
-			// 添加成功记录
-			addTaskToHistory(task, "completed", "")
+			task.checkSpace = true
+			warning = "没有预检查记录，将在开始下载前估算大小并检查剩余空间"
 		}
-	}()
+	}
+	enqueueDownload(task)
+
+	response := gin.H{
+		"message": "下载任务已加入队列",
+		"task_id": task.ID,
+	}
+	if estimated > 0 {
+		response["estimated_size"] = estimated
+	}
+	if warning != "" {
+		response["warning"] = warning
+	}
+	c.JSON(http.StatusOK, response)
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": "下载任务已启动"})
+// 根据请求参数 id 查找任务，未指定时使用当前任务
+func taskFromQuery(c *gin.Context) *DownloadTask {
+	if id := c.Query("id"); id != "" {
+		return findTask(id)
+	}
+	return getCurrentTask()
 }
 
 // 获取下载进度
 func getDownloadProgress(c *gin.Context) {
-	progress := getCurrentProgress()
+	progress := getTaskProgress(taskFromQuery(c))
 	c.JSON(http.StatusOK, progress)
 }
 
 // 获取下载状态
 func getDownloadStatus(c *gin.Context) {
-	progress := getCurrentProgress()
+	progress := getTaskProgress(taskFromQuery(c))
 	c.JSON(http.StatusOK, progress)
 }
 
 // 停止下载
 func stopDownload(c *gin.Context) {
-	stopCurrentDownload()
+	if task := taskFromQuery(c); task != nil {
+		stopTask(task)
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "下载已停止"})
 }
 
 // 暂停下载
 func pauseDownload(c *gin.Context) {
-	if pauseCurrentDownload() {
+	task := taskFromQuery(c)
+	if task != nil && pauseTask(task) {
 		c.JSON(http.StatusOK, gin.H{"message": "下载已暂停"})
 	} else {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "没有正在进行的下载任务"})
@@ -290,24 +412,291 @@ func pauseDownload(c *gin.Context) {
 
 // 继续下载
 func resumeDownload(c *gin.Context) {
-	if resumeCurrentDownload() {
+	task := taskFromQuery(c)
+	if task != nil && resumeTask(task) {
 		c.JSON(http.StatusOK, gin.H{"message": "下载已继续"})
 	} else {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "没有暂停的下载任务"})
 	}
 }
 
+// 获取任务列表
+func listTasks(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"tasks": listTaskInfos()})
+}
+
+// 根据路径参数查找任务，找不到时返回404
+func taskFromParam(c *gin.Context) *DownloadTask {
+	task := findTask(c.Param("id"))
+	if task == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+	}
+	return task
+}
+
+// 获取单个任务
+func getTask(c *gin.Context) {
+	task := taskFromParam(c)
+	if task == nil {
+		return
+	}
+	c.JSON(http.StatusOK, getTaskInfo(task))
+}
+
+// 获取任务的播放列表条目
+func getTaskItemsHandler(c *gin.Context) {
+	task := taskFromParam(c)
+	if task == nil {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": getTaskItems(task)})
+}
+
+// 暂停任务
+func pauseTaskHandler(c *gin.Context) {
+	task := taskFromParam(c)
+	if task == nil {
+		return
+	}
+	if !pauseTask(task) {
+		c.JSON(http.StatusConflict, gin.H{"error": "任务未在下载中，无法暂停"})
+		return
+	}
+	c.JSON(http.StatusOK, getTaskInfo(task))
+}
+
+// 设置任务限速，正在下载的任务会以新的限速继续下载
+func setTaskBandwidthHandler(c *gin.Context) {
+	task := taskFromParam(c)
+	if task == nil {
+		return
+	}
+
+	var req struct {
+		LimitKBps int `json:"limit_kbps"` // 0 表示使用配置中的默认值
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.LimitKBps < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	setTaskBandwidthLimit(task, req.LimitKBps)
+	c.JSON(http.StatusOK, getTaskInfo(task))
+}
+
+// 继续任务
+func resumeTaskHandler(c *gin.Context) {
+	task := taskFromParam(c)
+	if task == nil {
+		return
+	}
+	if !resumeTask(task) {
+		c.JSON(http.StatusConflict, gin.H{"error": "任务未暂停，无法继续"})
+		return
+	}
+	c.JSON(http.StatusOK, getTaskInfo(task))
+}
+
+// 停止任务
+func stopTaskHandler(c *gin.Context) {
+	task := taskFromParam(c)
+	if task == nil {
+		return
+	}
+	if !stopTask(task) {
+		c.JSON(http.StatusConflict, gin.H{"error": "任务已结束"})
+		return
+	}
+	c.JSON(http.StatusOK, getTaskInfo(task))
+}
+
+// 重试任务
+func retryTaskHandler(c *gin.Context) {
+	task := taskFromParam(c)
+	if task == nil {
+		return
+	}
+	if !retryTask(task) {
+		c.JSON(http.StatusConflict, gin.H{"error": "任务尚未结束，无法重试"})
+		return
+	}
+	c.JSON(http.StatusOK, getTaskInfo(task))
+}
+
+// 删除任务
+func deleteTaskHandler(c *gin.Context) {
+	task := taskFromParam(c)
+	if task == nil {
+		return
+	}
+	removeTask(task)
+	c.JSON(http.StatusOK, gin.H{"message": "任务已删除"})
+}
+
+// 解析历史记录时间参数，支持日期或日期时间格式
+// 仅指定日期时，isEnd 为 true 表示取当天结束时间
+func parseHistoryTime(value string, isEnd bool) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", value, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if isEnd {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// 从请求参数解析历史记录查询条件
+func parseHistoryQuery(c *gin.Context) (HistoryQuery, error) {
+	var q HistoryQuery
+
+	if status := c.Query("status"); status != "" {
+		q.Statuses = strings.Split(status, ",")
+	}
+	q.Keyword = strings.TrimSpace(c.Query("q"))
+
+	if from := c.Query("from"); from != "" {
+		t, err := parseHistoryTime(from, false)
+		if err != nil {
+			return q, fmt.Errorf("from 参数格式错误")
+		}
+		q.From = t
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := parseHistoryTime(to, true)
+		if err != nil {
+			return q, fmt.Errorf("to 参数格式错误")
+		}
+		q.To = t
+	}
+
+	if page := c.Query("page"); page != "" {
+		n, err := strconv.Atoi(page)
+		if err != nil || n < 1 {
+			return q, fmt.Errorf("page 参数错误")
+		}
+		q.Page = n
+	}
+	if pageSize := c.Query("page_size"); pageSize != "" {
+		n, err := strconv.Atoi(pageSize)
+		if err != nil || n < 0 {
+			return q, fmt.Errorf("page_size 参数错误")
+		}
+		q.PageSize = n
+	}
+
+	return q, nil
+}
+
 // 获取下载历史
 func getDownloadHistory(c *gin.Context) {
-	history := getTaskHistory()
+	q, err := parseHistoryQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	history, total := queryTaskHistory(q)
 	hasActive := hasActiveDownload()
 
 	c.JSON(http.StatusOK, gin.H{
 		"tasks":         history,
+		"total":         total,
+		"page":          max(q.Page, 1),
+		"page_size":     q.PageSize,
 		"hasActiveTask": hasActive,
 	})
 }
 
+// 删除单条下载历史
+func deleteDownloadHistory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	if !deleteHistoryEntry(id) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "历史记录不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "历史记录已删除"})
+}
+
+// 使用历史记录中的原始参数重新下载
+func retryDownloadHistory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	req, ok := getHistoryRequest(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "历史记录不存在"})
+		return
+	}
+
+	task, err := newDownloadTask(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	enqueueDownload(task)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "下载任务已重新加入队列",
+		"task_id": task.ID,
+	})
+}
+
+// 批量清除下载历史，支持与查询相同的筛选参数
+func clearDownloadHistory(c *gin.Context) {
+	q, err := parseHistoryQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	removed := clearTaskHistory(q)
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("已清除 %d 条历史记录", removed),
+		"removed": removed,
+	})
+}
+
+// 获取音频库的下载存档
+func getDownloadArchive(c *gin.Context) {
+	savePath := c.Query("save_path")
+	ids, err := loadDownloadArchive(savePath)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"save_path": savePath,
+		"enabled":   isDownloadArchiveEnabled(),
+		"entries":   ids,
+		"total":     len(ids),
+	})
+}
+
+// 清空音频库的下载存档，之后可以重新下载其中的视频
+func clearDownloadArchiveHandler(c *gin.Context) {
+	savePath := c.Query("save_path")
+	if err := clearDownloadArchive(savePath); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "下载存档已清空"})
+}
+
 // 恢复后台下载
 func resumeBackgroundDownload(c *gin.Context) {
 	if hasActiveDownload() {
@@ -353,6 +742,22 @@ func getConfig(c *gin.Context) {
 		"write_thumbnail": config.WriteThumbnail,
 		"has_cookies":     hasCookiesFile,
 		"cookies_valid":   cookiesValid,
+
+		"max_concurrent_downloads": config.MaxConcurrentDownloads,
+		"history_max_entries":      config.HistoryMaxEntries,
+		"history_max_days":         config.HistoryMaxDays,
+		"stall_timeout_seconds":    config.StallTimeoutSeconds,
+		"item_retry_attempts":      config.ItemRetryAttempts,
+		"item_retry_delay_seconds": config.ItemRetryDelaySeconds,
+		"download_archive":         config.DownloadArchive,
+		"min_free_space_mb":        config.MinFreeSpaceMB,
+		"library_quotas":           config.LibraryQuotas,
+		"download_windows":         config.DownloadWindows,
+
+		"bandwidth_limit_kbps":      config.BandwidthLimitKBps,
+		"task_bandwidth_limit_kbps": config.TaskBandwidthLimitKBps,
+		"proxy":                     config.Proxy,
+		"proxy_overrides":           config.ProxyOverrides,
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -360,12 +765,45 @@ func getConfig(c *gin.Context) {
 
 // 保存配置
 func saveConfig(c *gin.Context) {
-	var config Config
-	if err := c.ShouldBindJSON(&config); err != nil {
+	// 在现有配置基础上更新，未提交的字段保持原值
+	current, err := loadConfig()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "加载配置失败"})
+		return
+	}
+
+	// 先确认提交了哪些字段：JSON 解析到已有的 map 时只会添加键，
+	// 提交了 map 字段时需要先清空，才能删除其中的条目
+	var sent map[string]json.RawMessage
+	if err := c.ShouldBindBodyWith(&sent, binding.JSON); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
 		return
 	}
 
+	config := *current
+	if _, ok := sent["library_quotas"]; ok {
+		config.LibraryQuotas = nil
+	}
+	if _, ok := sent["proxy_overrides"]; ok {
+		config.ProxyOverrides = nil
+	}
+	if err := c.ShouldBindBodyWith(&config, binding.JSON); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	if _, err := parseDownloadWindows(config.DownloadWindows); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if config.BandwidthLimitKBps < 0 || config.TaskBandwidthLimitKBps < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "限速不能为负数"})
+		return
+	}
+	if err := validateProxyConfig(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	if err := saveConfigToFile(config); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存配置失败"})
 		return
@@ -503,6 +941,187 @@ func getUserInfo(c *gin.Context) {
 	c.JSON(http.StatusOK, userInfo)
 }
 
+// 获取收藏夹列表
+func getFavoriteFoldersHandler(c *gin.Context) {
+	if !hasCookies() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	folders, err := getFavoriteFolders()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取收藏夹失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"folders":     folders,
+		"watch_later": gin.H{"title": "稍后再看", "url": watchLaterURL},
+	})
+}
+
+// 获取收藏夹内容
+func getFavoriteFolderHandler(c *gin.Context) {
+	if !hasCookies() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的收藏夹ID"})
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的页码"})
+		return
+	}
+
+	contents, err := getFavoriteFolderContents(id, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取收藏夹内容失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, contents)
+}
+
+// 获取稍后再看列表
+func getWatchLaterHandler(c *gin.Context) {
+	if !hasCookies() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	list, err := getWatchLaterList()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取稍后再看失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// 获取所有订阅
+func listSubscriptionsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"subscriptions": listSubscriptions()})
+}
+
+// 获取单个订阅
+func getSubscriptionHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	sub, ok := getSubscription(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "订阅不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+// 创建订阅
+func createSubscriptionHandler(c *gin.Context) {
+	var req SubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	sub, err := createSubscription(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+// 预览订阅链接中符合筛选条件的条目，不保存订阅
+func previewSubscriptionHandler(c *gin.Context) {
+	var req SubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	preview, err := previewSubscription(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, preview)
+}
+
+// 更新订阅
+func updateSubscriptionHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	var req SubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	if _, ok := getSubscription(id); !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "订阅不存在"})
+		return
+	}
+	sub, err := updateSubscription(id, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+// 删除订阅
+func deleteSubscriptionHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	if !deleteSubscription(id) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "订阅不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "订阅已删除"})
+}
+
+// 立即检查订阅
+func checkSubscriptionHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	if _, ok := getSubscription(id); !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "订阅不存在"})
+		return
+	}
+	queued, err := checkSubscription(id)
+	sub, _ := getSubscription(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查订阅失败: " + err.Error(), "subscription": sub})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":      fmt.Sprintf("发现 %d 个新条目", queued),
+		"queued":       queued,
+		"subscription": sub,
+	})
+}
+
 // 导出Cookies
 func exportCookies(c *gin.Context) {
 	// 检查是否有cookies文件
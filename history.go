@@ -0,0 +1,358 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 默认历史记录保留条数
+const defaultHistoryMaxEntries = 1000
+
+// 任务历史结构
+type TaskHistory struct {
+	ID         int      `json:"id"`
+	TaskID     string   `json:"task_id,omitempty"`
+	Run        int      `json:"run,omitempty"` // 同一任务的第几次运行，重试后递增
+	Title      string   `json:"title"`
+	Status     string   `json:"status"`
+	URL        string   `json:"url"`
+	SavePath   string   `json:"save_path,omitempty"`
+	CreatedAt  string   `json:"created_at"`
+	FinishedAt string   `json:"finished_at,omitempty"`
+	FileSize   string   `json:"file_size,omitempty"`
+	TotalBytes int64    `json:"total_bytes,omitempty"`
+	Files      []string `json:"files,omitempty"`
+	Duration   string   `json:"duration,omitempty"`
+	Progress   int      `json:"progress,omitempty"`
+	Error      string   `json:"error,omitempty"`
+
+	Request *DownloadRequest `json:"request,omitempty"` // 任务的原始下载请求，用于重试
+}
+
+// 历史记录存储
+var (
+	taskHistoryMutex sync.RWMutex
+	taskHistoryList  []TaskHistory
+	nextTaskID       int = 1
+)
+
+// 获取历史记录文件路径
+func getHistoryPath() string {
+	return filepath.Join("config", "history.json")
+}
+
+// 初始化历史记录，从磁盘加载
+func initializeHistory() {
+	fmt.Println("初始化历史记录...")
+
+	taskHistoryMutex.Lock()
+	defer taskHistoryMutex.Unlock()
+
+	data, err := os.ReadFile(getHistoryPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("读取历史记录失败: %v\n", err)
+		}
+		return
+	}
+
+	if err := json.Unmarshal(data, &taskHistoryList); err != nil {
+		fmt.Printf("解析历史记录失败: %v\n", err)
+		taskHistoryList = nil
+		return
+	}
+
+	// 上次运行时未结束的任务标记为中断
+	changed := false
+	for i := range taskHistoryList {
+		item := &taskHistoryList[i]
+		if item.ID >= nextTaskID {
+			nextTaskID = item.ID + 1
+		}
+		if item.Status == "downloading" {
+			item.Status = "failed"
+			item.Error = "服务重启，任务被中断"
+			changed = true
+		}
+	}
+
+	if applyHistoryRetentionLocked() || changed {
+		if err := saveHistoryLocked(); err != nil {
+			fmt.Printf("保存历史记录失败: %v\n", err)
+		}
+	}
+
+	fmt.Printf("历史记录初始化完成，共加载 %d 条记录\n", len(taskHistoryList))
+}
+
+// 按配置裁剪历史记录，返回是否有记录被删除
+// 调用方需持有 taskHistoryMutex
+func applyHistoryRetentionLocked() bool {
+	maxEntries := defaultHistoryMaxEntries
+	maxDays := 0
+	if config, err := loadConfig(); err == nil {
+		if config.HistoryMaxEntries > 0 {
+			maxEntries = config.HistoryMaxEntries
+		}
+		maxDays = config.HistoryMaxDays
+	}
+
+	before := len(taskHistoryList)
+
+	// 删除超过保留天数的记录
+	if maxDays > 0 {
+		cutoff := time.Now().AddDate(0, 0, -maxDays)
+		taskHistoryList = slices.DeleteFunc(taskHistoryList, func(item TaskHistory) bool {
+			createdAt, err := time.ParseInLocation("2006-01-02 15:04:05", item.CreatedAt, time.Local)
+			return err == nil && createdAt.Before(cutoff)
+		})
+	}
+
+	// 只保留最近的记录
+	if len(taskHistoryList) > maxEntries {
+		taskHistoryList = slices.Clone(taskHistoryList[len(taskHistoryList)-maxEntries:])
+	}
+
+	return len(taskHistoryList) != before
+}
+
+// 将历史记录写入磁盘
+// 调用方需持有 taskHistoryMutex
+func saveHistoryLocked() error {
+	return writeJSONFile(getHistoryPath(), taskHistoryList)
+}
+
+// 计算已下载文件的总大小
+func sumFileSizes(dir string, files []string) int64 {
+	var total int64
+	for _, name := range files {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			total += info.Size()
+		}
+	}
+	return total
+}
+
+// 格式化文件大小
+func formatFileSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// 添加任务到历史记录，同一任务的每次运行（重试后为新的一次）对应一条记录
+func addTaskToHistory(task *DownloadTask, status string, errorMsg string) {
+	// 获取任务信息快照
+	downloadMutex.RLock()
+	title := "未知任务"
+	progress := 0
+	var fileSize, duration string
+	if task.Progress != nil {
+		if task.Progress.PlaylistTitle != "" {
+			title = task.Progress.PlaylistTitle
+		} else if task.Progress.CurrentTitle != "" {
+			title = task.Progress.CurrentTitle
+		}
+		progress = int(task.Progress.Progress)
+		fileSize = task.Progress.FileSize
+		duration = task.Progress.Duration
+	}
+	files := slices.Clone(task.DownloadedFiles)
+	createdAt := task.CreatedAt
+	request := task.Request
+	run := task.historyRun + 1
+	downloadMutex.RUnlock()
+
+	// 重试的运行从重新开始时计时
+	if createdAt.IsZero() || run > 1 {
+		createdAt = time.Now()
+	}
+
+	// 计算已下载文件大小
+	totalBytes := sumFileSizes(filepath.Join("audiobooks", task.SavePath), files)
+	if totalBytes > 0 {
+		fileSize = formatFileSize(totalBytes)
+	}
+
+	taskHistoryMutex.Lock()
+	defer taskHistoryMutex.Unlock()
+
+	index := slices.IndexFunc(taskHistoryList, func(item TaskHistory) bool {
+		return task.ID != "" && item.TaskID == task.ID && max(item.Run, 1) == run
+	})
+	if index == -1 {
+		taskHistoryList = append(taskHistoryList, TaskHistory{
+			ID:        nextTaskID,
+			TaskID:    task.ID,
+			Run:       run,
+			CreatedAt: createdAt.Format("2006-01-02 15:04:05"),
+		})
+		nextTaskID++
+		index = len(taskHistoryList) - 1
+	}
+
+	historyItem := &taskHistoryList[index]
+	historyItem.Title = title
+	historyItem.Status = status
+	historyItem.URL = task.URL
+	historyItem.SavePath = task.SavePath
+	historyItem.FileSize = fileSize
+	historyItem.TotalBytes = totalBytes
+	historyItem.Files = files
+	historyItem.Duration = duration
+	historyItem.Progress = progress
+	historyItem.Error = errorMsg
+	historyItem.Request = &request
+	historyItem.FinishedAt = ""
+	if status != "downloading" {
+		historyItem.FinishedAt = time.Now().Format("2006-01-02 15:04:05")
+	}
+
+	fmt.Printf("更新任务历史记录: ID=%d, Title=%s, Status=%s\n", historyItem.ID, historyItem.Title, historyItem.Status)
+
+	applyHistoryRetentionLocked()
+	if err := saveHistoryLocked(); err != nil {
+		fmt.Printf("保存历史记录失败: %v\n", err)
+	}
+}
+
+// 获取任务历史
+func getTaskHistory() []TaskHistory {
+	taskHistoryMutex.RLock()
+	defer taskHistoryMutex.RUnlock()
+
+	// 返回真实历史记录，按时间倒序排列
+	result := make([]TaskHistory, len(taskHistoryList))
+	copy(result, taskHistoryList)
+
+	// 简单的倒序排列
+	slices.Reverse(result)
+
+	return result
+}
+
+// 获取历史记录对应的下载请求
+// 早期记录没有保存原始请求时，使用记录中的链接和保存路径
+func getHistoryRequest(id int) (DownloadRequest, bool) {
+	taskHistoryMutex.RLock()
+	defer taskHistoryMutex.RUnlock()
+
+	for _, item := range taskHistoryList {
+		if item.ID != id {
+			continue
+		}
+		if item.Request != nil {
+			return *item.Request, true
+		}
+		return DownloadRequest{URL: item.URL, SavePath: item.SavePath}, true
+	}
+	return DownloadRequest{}, false
+}
+
+// 历史记录查询条件
+type HistoryQuery struct {
+	Statuses []string  // 状态筛选，为空表示全部
+	From     time.Time // 创建时间下限（含）
+	To       time.Time // 创建时间上限（不含）
+	Keyword  string    // 标题或URL关键字
+	Page     int       // 页码，从1开始
+	PageSize int       // 每页条数，0 表示不分页
+}
+
+// 判断历史记录是否符合查询条件
+func (q HistoryQuery) matches(item TaskHistory) bool {
+	if len(q.Statuses) > 0 && !slices.Contains(q.Statuses, item.Status) {
+		return false
+	}
+
+	if !q.From.IsZero() || !q.To.IsZero() {
+		createdAt, err := time.ParseInLocation("2006-01-02 15:04:05", item.CreatedAt, time.Local)
+		if err != nil {
+			return false
+		}
+		if !q.From.IsZero() && createdAt.Before(q.From) {
+			return false
+		}
+		if !q.To.IsZero() && !createdAt.Before(q.To) {
+			return false
+		}
+	}
+
+	if q.Keyword != "" {
+		keyword := strings.ToLower(q.Keyword)
+		if !strings.Contains(strings.ToLower(item.Title), keyword) &&
+			!strings.Contains(strings.ToLower(item.URL), keyword) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// 按条件查询历史记录，按时间倒序返回当前页及符合条件的总数
+func queryTaskHistory(q HistoryQuery) ([]TaskHistory, int) {
+	result := slices.DeleteFunc(getTaskHistory(), func(item TaskHistory) bool {
+		return !q.matches(item)
+	})
+	total := len(result)
+
+	if q.PageSize > 0 {
+		page := max(q.Page, 1)
+		start := min((page-1)*q.PageSize, total)
+		end := min(start+q.PageSize, total)
+		result = result[start:end]
+	}
+
+	return result, total
+}
+
+// 删除单条历史记录
+func deleteHistoryEntry(id int) bool {
+	taskHistoryMutex.Lock()
+	defer taskHistoryMutex.Unlock()
+
+	index := slices.IndexFunc(taskHistoryList, func(item TaskHistory) bool {
+		return item.ID == id
+	})
+	if index == -1 {
+		return false
+	}
+
+	taskHistoryList = slices.Delete(taskHistoryList, index, index+1)
+	if err := saveHistoryLocked(); err != nil {
+		fmt.Printf("保存历史记录失败: %v\n", err)
+	}
+	return true
+}
+
+// 批量删除符合条件的历史记录，返回删除条数
+func clearTaskHistory(q HistoryQuery) int {
+	taskHistoryMutex.Lock()
+	defer taskHistoryMutex.Unlock()
+
+	before := len(taskHistoryList)
+	taskHistoryList = slices.DeleteFunc(taskHistoryList, q.matches)
+	removed := before - len(taskHistoryList)
+
+	if removed > 0 {
+		if err := saveHistoryLocked(); err != nil {
+			fmt.Printf("保存历史记录失败: %v\n", err)
+		}
+	}
+
+	fmt.Printf("清除历史记录 %d 条\n", removed)
+	return removed
+}
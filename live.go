@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 任务类型
+const (
+	TaskTypeDownload = "download" // 普通下载
+	TaskTypeLive     = "live"     // 直播录制
+)
+
+// 直播间链接，如 https://live.bilibili.com/12345 或 https://live.bilibili.com/h5/12345
+var liveRoomRegex = regexp.MustCompile(`live\.bilibili\.com/(?:h5/)?(\d+)`)
+
+// 直播流中断后重新连接前的等待时间
+const liveReconnectDelay = 5 * time.Second
+
+// 直播间信息
+type liveRoomInfo struct {
+	RoomID     int64  `json:"room_id"`
+	Title      string `json:"title"`
+	LiveStatus int    `json:"live_status"` // 1 表示直播中
+}
+
+// 解析直播间链接，返回房间号
+func parseLiveRoomURL(inputURL string) (string, bool) {
+	match := liveRoomRegex.FindStringSubmatch(inputURL)
+	if len(match) != 2 {
+		return "", false
+	}
+	return match[1], true
+}
+
+// 直播间的规范链接
+func liveRoomURL(roomID string) string {
+	return fmt.Sprintf("https://live.bilibili.com/%s", roomID)
+}
+
+// 获取直播间信息
+func getLiveRoomInfo(roomID string) (*liveRoomInfo, error) {
+	var room liveRoomInfo
+	if err := getBilibiliAPI("https://api.live.bilibili.com/room/v1/Room/get_info?room_id="+roomID, &room); err != nil {
+		return nil, fmt.Errorf("获取直播间信息失败: %v", err)
+	}
+	return &room, nil
+}
+
+// 获取直播间预检查信息
+func getLiveRoomPreCheck(roomID string) (*PreCheckResponse, error) {
+	room, err := getLiveRoomInfo(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &PreCheckResponse{
+		Title:      room.Title,
+		Duration:   "未开播",
+		AudioCount: 1,
+		IsLive:     room.LiveStatus == 1,
+	}
+	if response.IsLive {
+		response.Duration = "直播中"
+	}
+	return response, nil
+}
+
+// 使用 yt-dlp 解析直播流地址
+func resolveLiveStream(roomURL string) (string, error) {
+	// 音频在各清晰度下相同，选择最低清晰度以节省带宽
+	args := []string{"-g", "-f", "worst", "--no-warnings"}
+	if hasCookies() {
+		args = append(args, "--cookies", getCookiesPath())
+	}
+	args = append(args, ytDlpProxyArgs()...)
+	args = append(args, roomURL)
+
+	output, err := exec.Command(getYtDlpPath(), args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("解析直播流失败: %v", err)
+	}
+	streamURL := strings.TrimSpace(strings.SplitN(string(output), "\n", 2)[0])
+	if streamURL == "" {
+		return "", fmt.Errorf("解析直播流失败: 没有可用的直播流")
+	}
+	return streamURL, nil
+}
+
+// 文件名中不允许的字符
+var unsafeFileNameRegex = regexp.MustCompile(`[\\/:*?"<>|%\s]+`)
+
+// 录制文件名前缀
+func liveFilePrefix(title, roomID string) string {
+	name := strings.Trim(unsafeFileNameRegex.ReplaceAllString(title, "_"), "_")
+	if name == "" {
+		name = "live"
+	}
+	return fmt.Sprintf("%s_%s", name, roomID)
+}
+
+// 查找本次录制生成的文件
+func findLiveFiles(savePath, prefix string, since time.Time) []string {
+	matches, _ := filepath.Glob(filepath.Join(savePath, prefix+"_*.aac"))
+	var files []string
+	for _, path := range matches {
+		if info, err := os.Stat(path); err == nil && !info.ModTime().Before(since.Add(-time.Second)) {
+			files = append(files, filepath.Base(path))
+		}
+	}
+	return files
+}
+
+// 录制直播音频，直到直播结束、达到时长限制或任务被停止
+func recordLive(task *DownloadTask) error {
+	ffmpegPath, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return fmt.Errorf("录制直播需要安装 ffmpeg")
+	}
+	if _, err := liveRecorderProxy(); err != nil {
+		return err
+	}
+	roomID, _ := parseLiveRoomURL(task.URL)
+
+	downloadMutex.Lock()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	task.Cancel = cancel
+	isContinue := task.ResumePending
+	task.ResumePending = false
+	if task.MaxDuration > 0 && task.deadlineAt.IsZero() {
+		task.deadlineAt = time.Now().Add(task.MaxDuration)
+	}
+	deadlineAt := task.deadlineAt
+	if !isContinue {
+		task.DownloadedFiles = nil
+		task.liveRecorded = 0
+		task.Progress = &DownloadProgress{
+			TaskID:         task.ID,
+			TaskState:      task.State,
+			IsDownloading:  true,
+			IsLive:         true,
+			Status:         "准备录制直播...",
+			LastActivity:   "初始化录制任务",
+			CompletedFiles: make([]string, 0),
+			StartTime:      time.Now().Format("2006-01-02 15:04:05"),
+			Phase:          "initializing",
+		}
+	} else {
+		task.Progress.IsDownloading = true
+		task.Progress.IsPaused = false
+		task.Progress.Status = "继续录制..."
+		task.Progress.Phase = "recording"
+	}
+	downloadMutex.Unlock()
+	broadcastProgress(task)
+
+	// 磁盘空间不足或超出配额时暂停任务
+	if err := checkDiskLimits(task.SavePath); err != nil {
+		pauseTaskWithReason(task, err.Error())
+		return err
+	}
+
+	savePath := filepath.Join("audiobooks", task.SavePath)
+	if err := os.MkdirAll(savePath, 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %v", err)
+	}
+
+	for {
+		downloadMutex.RLock()
+		running := task.State == TaskStateRunning
+		recordedAny := len(task.DownloadedFiles) > 0
+		downloadMutex.RUnlock()
+		if !running || ctx.Err() != nil {
+			return fmt.Errorf("录制被取消")
+		}
+		if !deadlineAt.IsZero() && !time.Now().Before(deadlineAt) {
+			setLiveStatus(task, "已达到录制时长限制")
+			return nil
+		}
+
+		room, err := getLiveRoomInfo(roomID)
+		if err != nil {
+			return err
+		}
+		if room.LiveStatus != 1 {
+			if recordedAny {
+				setLiveStatus(task, "直播已结束")
+				return nil
+			}
+			return fmt.Errorf("直播间 %s 未开播", roomID)
+		}
+
+		streamURL, err := resolveLiveStream(task.URL)
+		if err != nil {
+			return err
+		}
+
+		if err := runLiveRecorder(ctx, task, ffmpegPath, streamURL, room, savePath, deadlineAt); err != nil {
+			fmt.Printf("直播录制中断: %v\n", err)
+		}
+
+		// 直播流断开后确认直播是否仍在进行，仍在直播时重新连接
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("录制被取消")
+		case <-time.After(liveReconnectDelay):
+		}
+	}
+}
+
+// 设置录制结束时的状态说明
+func setLiveStatus(task *DownloadTask, status string) {
+	downloadMutex.Lock()
+	task.Progress.LastActivity = status
+	downloadMutex.Unlock()
+	fmt.Printf("直播录制任务 %s: %s\n", task.ID, status)
+}
+
+// 获取传给 ffmpeg 的代理，ffmpeg 只支持 HTTP 代理，配置了其他代理时返回错误以免直连录制
+func liveRecorderProxy() (string, error) {
+	proxy := getProxy(ProxyPurposeYtDlp)
+	if proxy == "" || proxy == proxyDirect {
+		return "", nil
+	}
+	if !strings.HasPrefix(proxy, "http://") {
+		return "", fmt.Errorf("录制直播使用的 ffmpeg 只支持 http:// 代理，当前代理为 %s", proxy)
+	}
+	return proxy, nil
+}
+
+// 启动 ffmpeg 录制一次直播流，直到流断开、达到时长限制或任务被停止
+func runLiveRecorder(ctx context.Context, task *DownloadTask, ffmpegPath, streamURL string, room *liveRoomInfo, savePath string, deadlineAt time.Time) error {
+	prefix := liveFilePrefix(room.Title, strconv.FormatInt(room.RoomID, 10))
+	sessionStart := time.Now()
+
+	args := []string{
+		"-hide_banner", "-nostats", "-loglevel", "error",
+		"-progress", "pipe:1",
+		"-user_agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36",
+		"-headers", "Referer: https://live.bilibili.com/\r\n",
+		// 超过30秒没有收到数据视为断流
+		"-rw_timeout", "30000000",
+	}
+	if proxy, _ := liveRecorderProxy(); proxy != "" {
+		args = append(args, "-http_proxy", proxy)
+	}
+	args = append(args,
+		"-i", streamURL,
+		// 只保留音频，不重新编码；ADTS 格式即使进程被终止也能正常播放
+		"-vn", "-c:a", "copy",
+	)
+	if task.SegmentMinutes > 0 {
+		args = append(args,
+			"-f", "segment",
+			"-segment_time", strconv.Itoa(task.SegmentMinutes*60),
+			"-reset_timestamps", "1",
+			"-strftime", "1",
+			filepath.Join(savePath, prefix+"_%Y%m%d_%H%M%S.aac"),
+		)
+	} else {
+		args = append(args, "-f", "adts", filepath.Join(savePath, prefix+"_"+sessionStart.Format("20060102_150405")+".aac"))
+	}
+
+	cmd := exec.Command(ffmpegPath, args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("创建stdout管道失败: %v", err)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("创建stdin管道失败: %v", err)
+	}
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("启动ffmpeg失败: %v", err)
+	}
+
+	downloadMutex.Lock()
+	task.Cmd = cmd
+	task.Progress.CurrentTitle = room.Title
+	task.Progress.PlaylistTitle = room.Title
+	task.Progress.Status = "录制中..."
+	task.Progress.Phase = "recording"
+	task.Progress.LastActivity = "已连接直播流"
+	cancelled := task.State != TaskStateRunning
+	downloadMutex.Unlock()
+
+	if cancelled {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return fmt.Errorf("录制被取消")
+	}
+	fmt.Printf("ffmpeg已启动，PID: %d\n", cmd.Process.Pid)
+
+	parsed := make(chan struct{})
+	go func() {
+		defer close(parsed)
+		parseLiveProgress(task, stdout, savePath, prefix, sessionStart)
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		<-parsed
+		done <- cmd.Wait()
+	}()
+
+	var deadline <-chan time.Time
+	if !deadlineAt.IsZero() {
+		timer := time.NewTimer(time.Until(deadlineAt))
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	diskCheck := time.NewTicker(diskCheckInterval)
+	defer diskCheck.Stop()
+
+	var result error
+wait:
+	for {
+		select {
+		case result = <-done:
+			break wait
+		case <-diskCheck.C:
+			// 磁盘空间不足或超出配额时暂停录制，暂停会让 ffmpeg 正常退出
+			if err := checkDiskLimits(task.SavePath); err != nil {
+				pauseTaskWithReason(task, err.Error())
+			}
+		case <-deadline:
+			// 通知 ffmpeg 正常退出，写完缓冲的数据
+			io.WriteString(stdin, "q")
+			select {
+			case result = <-done:
+			case <-time.After(10 * time.Second):
+				cmd.Process.Kill()
+				result = <-done
+			}
+			break wait
+		case <-ctx.Done():
+			cmd.Process.Kill()
+			result = <-done
+			break wait
+		}
+	}
+
+	// 累计本次录制的时长和文件
+	downloadMutex.Lock()
+	task.Cmd = nil
+	task.liveRecorded += task.liveSession
+	task.liveSession = 0
+	for _, file := range findLiveFiles(savePath, prefix, sessionStart) {
+		if !slices.Contains(task.DownloadedFiles, file) {
+			task.DownloadedFiles = append(task.DownloadedFiles, file)
+		}
+	}
+	downloadMutex.Unlock()
+
+	return result
+}
+
+// 解析 ffmpeg -progress 输出，更新已录制时长和大小
+func parseLiveProgress(task *DownloadTask, pipe io.Reader, savePath, prefix string, sessionStart time.Time) {
+	scanner := bufio.NewScanner(pipe)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "out_time_us":
+			if us, err := strconv.ParseInt(value, 10, 64); err == nil && us > 0 {
+				downloadMutex.Lock()
+				task.liveSession = time.Duration(us) * time.Microsecond
+				downloadMutex.Unlock()
+			}
+		case "progress":
+			// 每组进度信息以 progress= 结束
+			files := findLiveFiles(savePath, prefix, sessionStart)
+			sessionBytes := sumFileSizes(savePath, files)
+
+			downloadMutex.Lock()
+			if !task.IsRunning {
+				downloadMutex.Unlock()
+				continue
+			}
+			elapsed := task.liveRecorded + task.liveSession
+			recordedBytes := sumFileSizes(savePath, task.DownloadedFiles) + sessionBytes
+			p := task.Progress
+			p.ElapsedSeconds = int(elapsed.Seconds())
+			p.Elapsed = formatDuration(elapsed.Seconds())
+			p.Duration = p.Elapsed
+			p.DownloadedBytes = recordedBytes
+			p.FileSize = formatFileSize(recordedBytes)
+			if len(files) > 0 {
+				p.CurrentFile = files[len(files)-1]
+			}
+			p.Status = fmt.Sprintf("录制中: %s，已录制 %s", p.Elapsed, p.FileSize)
+			downloadMutex.Unlock()
+
+			broadcastProgress(task)
+		}
+	}
+}
@@ -29,6 +29,12 @@ func main() {
 	// 初始化历史记录
 	initializeHistory()
 
+	// 加载订阅并启动定时检查
+	initializeSubscriptions()
+
+	// 按计划开始时间和下载时段调度任务
+	go runDownloadScheduler()
+
 	// 设置 Gin 模式
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
@@ -48,7 +54,7 @@ func main() {
 
 	r.Use(cors.New(cors.Config{
 		AllowOrigins: corsOrigins,
-		AllowMethods: []string{"GET", "POST", "OPTIONS"},
+		AllowMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowHeaders: []string{
 			"Origin",
 			"Content-Type",
@@ -137,7 +143,23 @@ func main() {
 		api.POST("/download/pause", pauseDownload)
 		api.POST("/download/resume", resumeDownload)
 		api.GET("/download/history", getDownloadHistory)
+		api.DELETE("/download/history", clearDownloadHistory)
+		api.DELETE("/download/history/:id", deleteDownloadHistory)
+		api.POST("/download/history/:id/retry", retryDownloadHistory)
 		api.POST("/download/resume-background", resumeBackgroundDownload)
+		api.GET("/download/archive", getDownloadArchive)
+		api.DELETE("/download/archive", clearDownloadArchiveHandler)
+
+		// 任务管理
+		api.GET("/tasks", listTasks)
+		api.GET("/tasks/:id", getTask)
+		api.GET("/tasks/:id/items", getTaskItemsHandler)
+		api.POST("/tasks/:id/pause", pauseTaskHandler)
+		api.POST("/tasks/:id/resume", resumeTaskHandler)
+		api.POST("/tasks/:id/stop", stopTaskHandler)
+		api.POST("/tasks/:id/retry", retryTaskHandler)
+		api.POST("/tasks/:id/bandwidth", setTaskBandwidthHandler)
+		api.DELETE("/tasks/:id", deleteTaskHandler)
 
 		// 配置相关
 		api.GET("/config", getConfig)
@@ -152,6 +174,20 @@ func main() {
 		api.GET("/user/info", getUserInfo)
 		api.GET("/auth/export-cookies", exportCookies)
 		api.POST("/auth/logout", logoutUser)
+
+		// 收藏夹和稍后再看
+		api.GET("/favorites", getFavoriteFoldersHandler)
+		api.GET("/favorites/:id", getFavoriteFolderHandler)
+		api.GET("/watchlater", getWatchLaterHandler)
+
+		// 订阅相关
+		api.GET("/subscriptions", listSubscriptionsHandler)
+		api.POST("/subscriptions", createSubscriptionHandler)
+		api.POST("/subscriptions/preview", previewSubscriptionHandler)
+		api.GET("/subscriptions/:id", getSubscriptionHandler)
+		api.PUT("/subscriptions/:id", updateSubscriptionHandler)
+		api.DELETE("/subscriptions/:id", deleteSubscriptionHandler)
+		api.POST("/subscriptions/:id/check", checkSubscriptionHandler)
 	}
 
 	// WebSocket 路由
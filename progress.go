@@ -0,0 +1,499 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// yt-dlp 结构化输出的行前缀
+const (
+	ytDlpProgressPrefix = "[lazybala] progress "
+	ytDlpItemPrefix     = "[lazybala] item "
+	ytDlpFilePrefix     = "[lazybala] file "
+)
+
+// yt-dlp 进度模板：progress 和 info 两个 JSON 对象，以空格分隔
+const ytDlpProgressTemplate = "download:" + ytDlpProgressPrefix +
+	"%(progress.{status,downloaded_bytes,total_bytes,total_bytes_estimate,speed,eta,elapsed,filename})j " +
+	"%(info.{id,title,playlist_index,n_entries,playlist_title,duration,uploader,view_count,thumbnail})j"
+
+// yt-dlp 开始处理条目时输出的信息
+const ytDlpItemTemplate = "video:" + ytDlpItemPrefix +
+	"%(.{id,title,playlist_index,n_entries,duration})j"
+
+// yt-dlp 文件移动到最终位置后输出的信息
+const ytDlpFileTemplate = "after_move:" + ytDlpFilePrefix +
+	"%(.{id,title,filepath,playlist_index,n_entries,duration})j"
+
+// yt-dlp 进度信息
+type ytDlpProgress struct {
+	Status             string  `json:"status"`
+	DownloadedBytes    float64 `json:"downloaded_bytes"`
+	TotalBytes         float64 `json:"total_bytes"`
+	TotalBytesEstimate float64 `json:"total_bytes_estimate"`
+	Speed              float64 `json:"speed"`
+	ETA                float64 `json:"eta"`
+	Elapsed            float64 `json:"elapsed"`
+	Filename           string  `json:"filename"`
+}
+
+// 总字节数，未知时使用估计值
+func (p ytDlpProgress) total() int64 {
+	if p.TotalBytes > 0 {
+		return int64(p.TotalBytes)
+	}
+	return int64(p.TotalBytesEstimate)
+}
+
+// yt-dlp 条目信息
+type ytDlpInfo struct {
+	ID            string  `json:"id"`
+	Title         string  `json:"title"`
+	Filepath      string  `json:"filepath"`
+	PlaylistIndex int     `json:"playlist_index"`
+	NEntries      int     `json:"n_entries"`
+	PlaylistTitle string  `json:"playlist_title"`
+	Duration      float64 `json:"duration"`
+	Uploader      string  `json:"uploader"`
+	ViewCount     int64   `json:"view_count"`
+	Thumbnail     string  `json:"thumbnail"`
+}
+
+// 解析进度行，返回进度和条目信息
+func parseYtDlpProgressLine(line string) (*ytDlpProgress, *ytDlpInfo, bool) {
+	idx := strings.Index(line, ytDlpProgressPrefix)
+	if idx == -1 {
+		return nil, nil, false
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(line[idx+len(ytDlpProgressPrefix):]))
+	var progress ytDlpProgress
+	var info ytDlpInfo
+	if err := decoder.Decode(&progress); err != nil {
+		return nil, nil, false
+	}
+	if err := decoder.Decode(&info); err != nil {
+		return nil, nil, false
+	}
+	return &progress, &info, true
+}
+
+// 解析带指定前缀的条目信息行
+func parseYtDlpInfoLine(line, prefix string) (*ytDlpInfo, bool) {
+	idx := strings.Index(line, prefix)
+	if idx == -1 {
+		return nil, false
+	}
+
+	var info ytDlpInfo
+	if err := json.Unmarshal([]byte(line[idx+len(prefix):]), &info); err != nil {
+		return nil, false
+	}
+	return &info, true
+}
+
+// 错误信息中的条目ID，如 "[BiliBili] BV1xx411c7mD: ..."
+var ytDlpErrorItemRegex = regexp.MustCompile(`^\[[^\]]+\] ([^\s:]+): `)
+
+// 播放列表条目状态
+const (
+	ItemStatePending     = "pending"
+	ItemStateDownloading = "downloading"
+	ItemStateSkipped     = "skipped"
+	ItemStateDone        = "done"
+	ItemStateFailed      = "failed"
+)
+
+// 播放列表条目
+type DownloadItem struct {
+	Index    int     `json:"index"`
+	ID       string  `json:"id,omitempty"`
+	Title    string  `json:"title,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+	Status   string  `json:"status"`
+	Path     string  `json:"path,omitempty"`
+	Error    string  `json:"error,omitempty"`
+
+	noRetry bool // 重试也无法成功的失败（如需要大会员），不参与自动重试
+}
+
+// 按序号查找条目，不存在时按序号顺序插入，调用方需持有 downloadMutex
+func findOrCreateItemLocked(task *DownloadTask, index int) *DownloadItem {
+	pos, found := slices.BinarySearchFunc(task.Items, index, func(item *DownloadItem, target int) int {
+		return item.Index - target
+	})
+	if found {
+		return task.Items[pos]
+	}
+
+	item := &DownloadItem{Index: index, Status: ItemStatePending}
+	task.Items = slices.Insert(task.Items, pos, item)
+	return item
+}
+
+// 获取条目信息对应的条目并更新元数据，调用方需持有 downloadMutex
+func itemForInfoLocked(task *DownloadTask, info *ytDlpInfo) *DownloadItem {
+	// 单个视频没有播放列表序号
+	index := max(info.PlaylistIndex, 1)
+
+	// 首次得知条目总数时创建占位条目，选择了部分条目时只为选中的序号创建
+	if info.NEntries > 0 && len(task.Items) == 0 {
+		for i := 1; i <= info.NEntries; i++ {
+			if task.Selection.IsEmpty() || task.Selection.ContainsIndex(i) {
+				findOrCreateItemLocked(task, i)
+			}
+		}
+	}
+
+	item := findOrCreateItemLocked(task, index)
+	if info.ID != "" {
+		item.ID = info.ID
+	}
+	if info.Title != "" {
+		item.Title = info.Title
+	}
+	if info.Duration > 0 {
+		item.Duration = info.Duration
+	}
+	return item
+}
+
+// 按条目ID查找条目，调用方需持有 downloadMutex
+func findItemByIDLocked(task *DownloadTask, id string) *DownloadItem {
+	for _, item := range task.Items {
+		if item.ID == id {
+			return item
+		}
+	}
+	return nil
+}
+
+// 任务结束时整理条目状态：未完成的下载中条目标记为失败，调用方需持有 downloadMutex
+func finalizeItemsLocked(task *DownloadTask, errMsg string) {
+	for _, item := range task.Items {
+		if item.Status != ItemStateDownloading {
+			continue
+		}
+		item.Status = ItemStateFailed
+		if item.Error == "" {
+			item.Error = errMsg
+		}
+	}
+}
+
+// 下载成功结束后仍在等待的条目未被 yt-dlp 处理，按任务的筛选条件和下载存档设置标记为跳过
+// 返回跳过的条目数和原因，调用方需持有 downloadMutex
+func skipUnprocessedItemsLocked(task *DownloadTask) (int, string) {
+	var reasons []string
+	if !task.Filter.IsEmpty() {
+		reasons = append(reasons, "不符合标题关键字或上传日期范围")
+	}
+	if task.UseArchive {
+		reasons = append(reasons, "已在下载存档中")
+	}
+	reason := "未被下载"
+	if len(reasons) > 0 {
+		reason = strings.Join(reasons, "或")
+	}
+
+	skipped := 0
+	for _, item := range task.Items {
+		if item.Status == ItemStatePending {
+			item.Status = ItemStateSkipped
+			item.Error = reason
+			skipped++
+		}
+	}
+	return skipped, reason
+}
+
+// 暂停时下载中的条目恢复为等待状态，调用方需持有 downloadMutex
+func resetDownloadingItemsLocked(task *DownloadTask) {
+	for _, item := range task.Items {
+		if item.Status == ItemStateDownloading {
+			item.Status = ItemStatePending
+		}
+	}
+}
+
+// 复制条目列表
+func cloneItems(items []*DownloadItem) []DownloadItem {
+	result := make([]DownloadItem, 0, len(items))
+	for _, item := range items {
+		result = append(result, *item)
+	}
+	return result
+}
+
+// 获取任务条目列表的副本
+func getTaskItems(task *DownloadTask) []DownloadItem {
+	downloadMutex.RLock()
+	defer downloadMutex.RUnlock()
+	return cloneItems(task.Items)
+}
+
+// 计算整体进度：播放列表按 (已完成项目数 + 当前项目进度) / 总项目数 计算
+func overallProgress(p *DownloadProgress, fileProgress float64) float64 {
+	if p.TotalCount > 1 && p.CurrentIndex > 0 {
+		return (float64(p.CurrentIndex-1) + fileProgress/100.0) / float64(p.TotalCount) * 100.0
+	}
+	return fileProgress
+}
+
+// 更新条目信息，调用方需持有 downloadMutex
+func applyYtDlpInfo(p *DownloadProgress, info *ytDlpInfo) {
+	if info.PlaylistIndex > 0 {
+		p.CurrentIndex = info.PlaylistIndex
+	}
+	if info.NEntries > 0 {
+		p.TotalCount = info.NEntries
+	}
+	if info.PlaylistTitle != "" {
+		p.PlaylistTitle = info.PlaylistTitle
+	}
+	if info.Title != "" {
+		p.CurrentTitle = info.Title
+	}
+	if info.ID != "" {
+		p.CurrentID = info.ID
+	}
+	if info.Duration > 0 {
+		p.Duration = formatDuration(info.Duration)
+	}
+	if info.Uploader != "" {
+		p.Uploader = info.Uploader
+	}
+	if info.ViewCount > 0 {
+		p.ViewCount = fmt.Sprintf("%d", info.ViewCount)
+	}
+	if info.Thumbnail != "" {
+		p.Thumbnail = info.Thumbnail
+	}
+}
+
+// 条目开始处理，调用方需持有 downloadMutex
+func applyYtDlpItem(task *DownloadTask, info *ytDlpInfo) {
+	applyYtDlpInfo(task.Progress, info)
+
+	item := itemForInfoLocked(task, info)
+	item.Status = ItemStateDownloading
+	item.Error = ""
+
+	task.Progress.Status = fmt.Sprintf("准备下载: %s", info.Title)
+	task.Progress.Phase = "extracting"
+}
+
+// 更新下载进度，调用方需持有 downloadMutex
+func applyYtDlpProgress(task *DownloadTask, progress *ytDlpProgress, info *ytDlpInfo) {
+	p := task.Progress
+	applyYtDlpInfo(p, info)
+
+	if progress.Filename != "" {
+		p.CurrentFile = progress.Filename
+	}
+
+	total := progress.total()
+	p.DownloadedBytes = int64(progress.DownloadedBytes)
+	p.TotalBytes = total
+	if total > 0 {
+		p.FileSize = formatFileSize(total)
+	}
+
+	var fileProgress float64
+	switch {
+	case progress.Status == "finished":
+		fileProgress = 100.0
+	case total > 0:
+		fileProgress = min(float64(p.DownloadedBytes)/float64(total)*100.0, 100.0)
+	}
+
+	p.FileProgress = fileProgress
+	p.Progress = overallProgress(p, fileProgress)
+
+	p.SpeedBytes = progress.Speed
+	if progress.Speed > 0 {
+		p.Speed = formatFileSize(int64(progress.Speed)) + "/s"
+	}
+	p.ETASeconds = int(progress.ETA)
+	if progress.ETA > 0 {
+		p.ETA = formatDuration(progress.ETA)
+	} else {
+		p.ETA = ""
+	}
+
+	if progress.Status == "finished" {
+		p.Status = fmt.Sprintf("文件下载完成: %s", p.CurrentTitle)
+		p.Phase = "processing"
+	} else {
+		if p.TotalCount > 1 {
+			p.Status = fmt.Sprintf("整体进度: %.1f%% (第%d/%d项: %.1f%%)", p.Progress, p.CurrentIndex, p.TotalCount, fileProgress)
+		} else {
+			p.Status = fmt.Sprintf("下载中: %.1f%%", fileProgress)
+		}
+		p.Phase = "downloading"
+	}
+
+	// 记录当前条目已实际下载，用于区分跳过的文件
+	task.downloadingID = info.ID
+}
+
+// 记录完成的文件，调用方需持有 downloadMutex
+func applyYtDlpFile(task *DownloadTask, info *ytDlpInfo) {
+	p := task.Progress
+	applyYtDlpInfo(p, info)
+
+	savePath := filepath.Join("audiobooks", task.SavePath)
+	relPath, err := filepath.Rel(savePath, info.Filepath)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		relPath = filepath.Base(info.Filepath)
+	}
+	fileName := cleanUTF8String(filepath.Base(info.Filepath))
+
+	// 没有经过下载阶段的条目说明文件已存在
+	skipped := task.downloadingID != info.ID
+	task.downloadingID = ""
+
+	item := itemForInfoLocked(task, info)
+	// 继续下载时 yt-dlp 会再次报告之前已完成的文件，不能降级为跳过
+	alreadyDone := item.Status == ItemStateDone
+	item.Path = relPath
+	item.Error = ""
+
+	if alreadyDone {
+		p.Status = fmt.Sprintf("文件已保存: %s", fileName)
+		p.Phase = "downloading"
+	} else if skipped {
+		item.Status = ItemStateSkipped
+		p.Status = fmt.Sprintf("跳过已下载文件: %s", fileName)
+		p.Phase = "skipped"
+		fmt.Printf("跳过已下载文件: %s\n", fileName)
+	} else {
+		item.Status = ItemStateDone
+		p.Status = fmt.Sprintf("文件已保存: %s", fileName)
+		p.Phase = "downloading"
+		fmt.Printf("文件已保存: %s\n", info.Filepath)
+	}
+	p.FileProgress = 100.0
+	p.Progress = overallProgress(p, 100.0)
+
+	if !isAudioFile(fileName) {
+		return
+	}
+
+	if slices.Contains(task.DownloadedFiles, relPath) {
+		return
+	}
+	task.DownloadedFiles = append(task.DownloadedFiles, relPath)
+
+	// 更新最近完成列表（不区分大小写去重），只保留最近3个
+	for _, existing := range p.CompletedFiles {
+		if strings.EqualFold(cleanUTF8String(existing), fileName) {
+			return
+		}
+	}
+	p.CompletedFiles = append(p.CompletedFiles, fileName)
+	if len(p.CompletedFiles) > 3 {
+		p.CompletedFiles = p.CompletedFiles[len(p.CompletedFiles)-3:]
+	}
+}
+
+// 将错误关联到对应条目，返回是否有条目被标记为失败，调用方需持有 downloadMutex
+func applyYtDlpError(task *DownloadTask, msg string) bool {
+	var item *DownloadItem
+	if match := ytDlpErrorItemRegex.FindStringSubmatch(msg); len(match) > 1 {
+		item = findItemByIDLocked(task, match[1])
+	}
+	if item == nil && task.Progress.CurrentID != "" {
+		item = findItemByIDLocked(task, task.Progress.CurrentID)
+	}
+	if item == nil || item.Status == ItemStateDone || item.Status == ItemStateSkipped {
+		return false
+	}
+
+	item.Status = ItemStateFailed
+	item.Error = msg
+	if isVipOnlyError(msg) {
+		item.Error = fmt.Sprintf("该剧集为大会员专享，当前登录账号没有大会员权限（%s）", msg)
+		item.noRetry = true
+	}
+	return true
+}
+
+// 解析输出
+func parseOutput(task *DownloadTask, pipe io.Reader, source string) {
+	scanner := bufio.NewScanner(pipe)
+	scanner.Split(bufio.ScanLines)
+
+	// 设置更大的缓冲区以处理长行
+	const maxCapacity = 1024 * 1024 // 1MB
+	buf := make([]byte, maxCapacity)
+	scanner.Buffer(buf, maxCapacity)
+
+	lineCount := 0
+
+	for scanner.Scan() {
+		// 清理和验证UTF-8字符串
+		line := cleanUTF8String(scanner.Text())
+		lineCount++
+
+		downloadMutex.Lock()
+		if !task.IsRunning {
+			// 任务已暂停或停止，继续读取以排空管道
+			downloadMutex.Unlock()
+			continue
+		}
+
+		// 有新输出即视为有进展；进度行只有在字节数或条目变化时才算
+		active := true
+		itemsChanged := false
+
+		if progress, info, ok := parseYtDlpProgressLine(line); ok {
+			prevBytes, prevID := task.Progress.DownloadedBytes, task.Progress.CurrentID
+			applyYtDlpProgress(task, progress, info)
+			active = progress.Status == "finished" ||
+				task.Progress.DownloadedBytes != prevBytes ||
+				task.Progress.CurrentID != prevID
+		} else if info, ok := parseYtDlpInfoLine(line, ytDlpItemPrefix); ok {
+			applyYtDlpItem(task, info)
+			itemsChanged = true
+		} else if info, ok := parseYtDlpInfoLine(line, ytDlpFilePrefix); ok {
+			applyYtDlpFile(task, info)
+			itemsChanged = true
+		} else if msg, ok := strings.CutPrefix(line, "ERROR: "); ok {
+			itemsChanged = applyYtDlpError(task, msg)
+			task.Progress.Status = fmt.Sprintf("错误: %s", msg)
+			task.Progress.ErrorMessage = msg
+			task.Progress.LastActivity = fmt.Sprintf("ERROR: %s", msg)
+			task.Progress.Phase = "error"
+			fmt.Printf("[%s] 输出[%d]: %s\n", source, lineCount, line)
+		} else if msg, ok := strings.CutPrefix(line, "WARNING: "); ok {
+			task.Progress.WarningMessage = msg
+			task.Progress.LastActivity = fmt.Sprintf("WARNING: %s", msg)
+			fmt.Printf("[%s] 输出[%d]: %s\n", source, lineCount, line)
+		} else {
+			task.Progress.LastActivity = line
+			fmt.Printf("[%s] 输出[%d]: %s\n", source, lineCount, line)
+		}
+
+		if active {
+			task.lastOutputAt = time.Now()
+		}
+
+		downloadMutex.Unlock()
+
+		// 广播进度
+		broadcastProgress(task)
+		if itemsChanged {
+			broadcastItems(task)
+		}
+	}
+
+	fmt.Printf("[%s] 输出解析结束，共处理%d行\n", source, lineCount)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseYtDlpProgressLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		ok       bool
+		progress ytDlpProgress
+		info     ytDlpInfo
+		total    int64
+	}{
+		{
+			name:     "complete",
+			line:     `[lazybala] progress {"status": "downloading", "downloaded_bytes": 1024, "total_bytes": 4096, "total_bytes_estimate": null, "speed": 512.5, "eta": 6, "elapsed": 2.1, "filename": "a.m4a"} {"id": "BV1aa", "title": "第一集", "playlist_index": 1, "n_entries": 3, "playlist_title": "合集", "duration": 61, "uploader": "up", "view_count": 10, "thumbnail": null}`,
+			ok:       true,
+			progress: ytDlpProgress{Status: "downloading", DownloadedBytes: 1024, TotalBytes: 4096, Speed: 512.5, ETA: 6, Elapsed: 2.1, Filename: "a.m4a"},
+			info:     ytDlpInfo{ID: "BV1aa", Title: "第一集", PlaylistIndex: 1, NEntries: 3, PlaylistTitle: "合集", Duration: 61, Uploader: "up", ViewCount: 10},
+			total:    4096,
+		},
+		{
+			name:     "null fields",
+			line:     `[download] [lazybala] progress {"status": "downloading", "downloaded_bytes": 100, "total_bytes": null, "total_bytes_estimate": 2000.7, "speed": null, "eta": null, "elapsed": null, "filename": null} {"id": "BV1bb", "title": null, "playlist_index": null, "n_entries": null, "playlist_title": null, "duration": null, "uploader": null, "view_count": null, "thumbnail": null}`,
+			ok:       true,
+			progress: ytDlpProgress{Status: "downloading", DownloadedBytes: 100, TotalBytesEstimate: 2000.7},
+			info:     ytDlpInfo{ID: "BV1bb"},
+			total:    2000,
+		},
+		{
+			name: "no prefix",
+			line: `[download]  50.0% of 4.00MiB`,
+		},
+		{
+			name: "missing info",
+			line: `[lazybala] progress {"status": "finished"}`,
+		},
+		{
+			name: "invalid json",
+			line: `[lazybala] progress {"status": } {}`,
+		},
+	}
+	for _, tt := range tests {
+		progress, info, ok := parseYtDlpProgressLine(tt.line)
+		if ok != tt.ok {
+			t.Errorf("%s: ok = %v, want %v", tt.name, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if *progress != tt.progress {
+			t.Errorf("%s: progress = %+v, want %+v", tt.name, *progress, tt.progress)
+		}
+		if *info != tt.info {
+			t.Errorf("%s: info = %+v, want %+v", tt.name, *info, tt.info)
+		}
+		if got := progress.total(); got != tt.total {
+			t.Errorf("%s: total() = %d, want %d", tt.name, got, tt.total)
+		}
+	}
+}
+
+func TestApplyYtDlpFileKeepsDoneItems(t *testing.T) {
+	task := &DownloadTask{
+		ID:       "test",
+		SavePath: "test",
+		Progress: &DownloadProgress{},
+		Items: []*DownloadItem{
+			{Index: 1, ID: "BV1aa", Status: ItemStateDone},
+			{Index: 2, ID: "BV1bb", Status: ItemStatePending},
+		},
+	}
+	dir := filepath.Join("audiobooks", "test")
+
+	// 继续下载时 yt-dlp 再次报告已完成的第1项，不经过下载阶段
+	applyYtDlpFile(task, &ytDlpInfo{ID: "BV1aa", PlaylistIndex: 1, Filepath: filepath.Join(dir, "a.m4a")})
+	// 第2项文件已存在，跳过
+	applyYtDlpFile(task, &ytDlpInfo{ID: "BV1bb", PlaylistIndex: 2, Filepath: filepath.Join(dir, "b.m4a")})
+
+	want := []string{ItemStateDone, ItemStateSkipped}
+	for i, item := range task.Items {
+		if item.Status != want[i] {
+			t.Errorf("item %d status = %s, want %s", item.Index, item.Status, want[i])
+		}
+	}
+
+	// 重复报告同一文件时不重复记录
+	applyYtDlpFile(task, &ytDlpInfo{ID: "BV1aa", PlaylistIndex: 1, Filepath: filepath.Join(dir, "a.m4a")})
+	if len(task.DownloadedFiles) != 2 {
+		t.Errorf("DownloadedFiles = %v, want 2 entries", task.DownloadedFiles)
+	}
+}
+
+func TestSkipUnprocessedItemsLocked(t *testing.T) {
+	tests := []struct {
+		filter     CatalogFilter
+		useArchive bool
+		reason     string
+	}{
+		{CatalogFilter{}, false, "未被下载"},
+		{CatalogFilter{}, true, "已在下载存档中"},
+		{CatalogFilter{DateAfter: "20240101"}, false, "不符合标题关键字或上传日期范围"},
+		{CatalogFilter{TitleKeyword: "番外"}, true, "不符合标题关键字或上传日期范围或已在下载存档中"},
+	}
+	for _, tt := range tests {
+		task := &DownloadTask{
+			Filter:     tt.filter,
+			UseArchive: tt.useArchive,
+			Items: []*DownloadItem{
+				{Index: 1, Status: ItemStateDone},
+				{Index: 2, Status: ItemStatePending},
+				{Index: 3, Status: ItemStateFailed},
+			},
+		}
+		skipped, reason := skipUnprocessedItemsLocked(task)
+		if skipped != 1 || reason != tt.reason {
+			t.Errorf("skipUnprocessedItemsLocked(%+v, %v) = %d, %q, want 1, %q", tt.filter, tt.useArchive, skipped, reason, tt.reason)
+		}
+		want := []string{ItemStateDone, ItemStateSkipped, ItemStateFailed}
+		for i, item := range task.Items {
+			if item.Status != want[i] {
+				t.Errorf("item %d status = %s, want %s", item.Index, item.Status, want[i])
+			}
+		}
+	}
+}
+
+func TestParseOutputCountsRejectedEntriesAsProgress(t *testing.T) {
+	stale := time.Now().Add(-time.Hour)
+	task := &DownloadTask{ID: "test", IsRunning: true, Progress: &DownloadProgress{}, lastOutputAt: stale}
+
+	parseOutput(task, strings.NewReader("[download] BV1aa: 番外 does not pass filter (title~='(?i)正片'), skipping ..\n"), "yt-dlp")
+	if !task.lastOutputAt.After(stale) {
+		t.Errorf("lastOutputAt not updated by rejection message")
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// 代理用途，可在配置的 proxy_overrides 中为每种用途单独设置代理
+const (
+	ProxyPurposeBilibili = "bilibili" // 哔哩哔哩接口（登录、用户信息、收藏夹、番剧、直播间等）
+	ProxyPurposeGitHub   = "github"   // 版本检查和 yt-dlp 更新
+	ProxyPurposeYtDlp    = "ytdlp"    // yt-dlp 解析和下载，以及直播录制
+)
+
+// 代理设置为此值时不使用代理
+const proxyDirect = "direct"
+
+// 校验代理地址，支持 http、https、socks5 和 socks5h
+func validateProxy(proxy string) error {
+	if proxy == "" || proxy == proxyDirect {
+		return nil
+	}
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("无效的代理地址: %s", proxy)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+		return nil
+	default:
+		return fmt.Errorf("不支持的代理协议: %s，仅支持 http、https、socks5、socks5h", u.Scheme)
+	}
+}
+
+// 校验全部代理配置
+func validateProxyConfig(config *Config) error {
+	if err := validateProxy(config.Proxy); err != nil {
+		return err
+	}
+	for purpose, proxy := range config.ProxyOverrides {
+		switch purpose {
+		case ProxyPurposeBilibili, ProxyPurposeGitHub, ProxyPurposeYtDlp:
+		default:
+			return fmt.Errorf("未知的代理用途: %s", purpose)
+		}
+		if err := validateProxy(proxy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 获取指定用途使用的代理地址，为空表示未配置代理，direct 表示直接连接
+func getProxy(purpose string) string {
+	config, err := loadConfig()
+	if err != nil {
+		return ""
+	}
+	proxy := config.Proxy
+	if override, ok := config.ProxyOverrides[purpose]; ok && override != "" {
+		proxy = override
+	}
+	if validateProxy(proxy) != nil {
+		return ""
+	}
+	return proxy
+}
+
+// 创建使用指定用途代理的 HTTP 客户端，未配置代理时沿用环境变量中的代理设置
+func newHTTPClient(purpose string, timeout time.Duration) *http.Client {
+	client := &http.Client{Timeout: timeout}
+
+	proxy := getProxy(purpose)
+	if proxy == "" {
+		return client
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	if proxy != proxyDirect {
+		proxyURL, _ := url.Parse(proxy)
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	client.Transport = transport
+	return client
+}
+
+// yt-dlp 的代理参数，空字符串表示直接连接
+func ytDlpProxyArgs() []string {
+	switch proxy := getProxy(ProxyPurposeYtDlp); proxy {
+	case "":
+		return nil
+	case proxyDirect:
+		return []string{"--proxy", ""}
+	default:
+		return []string{"--proxy", proxy}
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// 检查计划开始时间和下载时段的间隔
+const downloadScheduleInterval = 30 * time.Second
+
+// 允许下载的时段，以当天零点起的分钟数表示；结束早于开始时表示跨越午夜
+type downloadWindow struct {
+	start int
+	end   int
+}
+
+// 解析 "HH:MM" 格式的时间，返回当天零点起的分钟数
+func parseClockMinutes(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("无效的时间: %s，应为 HH:MM 格式", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// 解析下载时段，格式如 "23:00-07:00"
+func parseDownloadWindows(values []string) ([]downloadWindow, error) {
+	windows := make([]downloadWindow, 0, len(values))
+	for _, value := range values {
+		startText, endText, ok := strings.Cut(value, "-")
+		if !ok {
+			return nil, fmt.Errorf("无效的下载时段: %s，应为 HH:MM-HH:MM 格式", value)
+		}
+		start, err := parseClockMinutes(startText)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClockMinutes(endText)
+		if err != nil {
+			return nil, err
+		}
+		if start == end {
+			return nil, fmt.Errorf("下载时段 %s 的开始和结束时间相同", value)
+		}
+		windows = append(windows, downloadWindow{start: start, end: end})
+	}
+	return windows, nil
+}
+
+// 判断时间是否在时段内
+func (w downloadWindow) contains(minutes int) bool {
+	if w.start < w.end {
+		return minutes >= w.start && minutes < w.end
+	}
+	return minutes >= w.start || minutes < w.end
+}
+
+// 获取配置的下载时段，未配置或配置无效时不限制
+func getDownloadWindows() []downloadWindow {
+	config, err := loadConfig()
+	if err != nil {
+		return nil
+	}
+	windows, err := parseDownloadWindows(config.DownloadWindows)
+	if err != nil {
+		fmt.Printf("下载时段配置无效，不限制下载时间: %v\n", err)
+		return nil
+	}
+	return windows
+}
+
+// 判断当前是否允许下载
+func isInDownloadWindow(windows []downloadWindow, now time.Time) bool {
+	if len(windows) == 0 {
+		return true
+	}
+	minutes := now.Hour()*60 + now.Minute()
+	return slices.ContainsFunc(windows, func(w downloadWindow) bool {
+		return w.contains(minutes)
+	})
+}
+
+// 下一个下载时段的开始时间
+func nextDownloadWindowStart(windows []downloadWindow, now time.Time) time.Time {
+	var next time.Time
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for _, w := range windows {
+		start := midnight.Add(time.Duration(w.start) * time.Minute)
+		if !start.After(now) {
+			start = start.AddDate(0, 0, 1)
+		}
+		if next.IsZero() || start.Before(next) {
+			next = start
+		}
+	}
+	return next
+}
+
+// 解析任务的计划开始时间
+func parseStartAt(value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无效的开始时间: %s，应为 YYYY-MM-DD HH:MM 格式", value)
+}
+
+// 排队中的任务暂不能开始的原因，可以开始时返回空字符串，调用方需持有 downloadMutex
+// 直播录制不受下载时段限制
+func taskWaitReasonLocked(task *DownloadTask, windows []downloadWindow, now time.Time) string {
+	if now.Before(task.StartAt) {
+		return fmt.Sprintf("计划于 %s 开始", task.StartAt.Format("2006-01-02 15:04"))
+	}
+	if task.Type != TaskTypeLive && !isInDownloadWindow(windows, now) {
+		return fmt.Sprintf("不在下载时段内，将于 %s 开始", nextDownloadWindowStart(windows, now).Format("15:04"))
+	}
+	return ""
+}
+
+// 定时按下载时段暂停和恢复任务，并启动到达计划时间的任务
+func runDownloadScheduler() {
+	ticker := time.NewTicker(downloadScheduleInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		applyDownloadWindows()
+		scheduleDownloads()
+	}
+}
+
+// 下载时段结束时暂停正在下载的任务，时段开始时恢复这些任务
+func applyDownloadWindows() {
+	allowed := isInDownloadWindow(getDownloadWindows(), time.Now())
+
+	downloadMutex.RLock()
+	var tasks []*DownloadTask
+	for _, task := range downloadTasks {
+		if task.Type == TaskTypeLive {
+			continue
+		}
+		if (!allowed && task.State == TaskStateRunning) || (allowed && task.State == TaskStatePaused && task.windowPaused) {
+			tasks = append(tasks, task)
+		}
+	}
+	downloadMutex.RUnlock()
+
+	for _, task := range tasks {
+		if allowed {
+			fmt.Printf("下载时段开始，继续任务 %s\n", task.ID)
+			resumeTask(task)
+		} else if pauseTaskWithReason(task, "不在下载时段内") {
+			fmt.Printf("下载时段结束，暂停任务 %s\n", task.ID)
+			downloadMutex.Lock()
+			task.windowPaused = true
+			downloadMutex.Unlock()
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDownloadWindows(t *testing.T) {
+	tests := []struct {
+		input   []string
+		want    []downloadWindow
+		wantErr bool
+	}{
+		{input: nil, want: []downloadWindow{}},
+		{input: []string{"01:00-07:30"}, want: []downloadWindow{{60, 450}}},
+		{input: []string{"23:00-07:00", " 12:00 - 13:00 "}, want: []downloadWindow{{1380, 420}, {720, 780}}},
+		{input: []string{"08:00"}, wantErr: true},
+		{input: []string{"25:00-07:00"}, wantErr: true},
+		{input: []string{"08:00-8pm"}, wantErr: true},
+		{input: []string{"08:00-08:00"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseDownloadWindows(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseDownloadWindows(%q) = %v, want error", tt.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseDownloadWindows(%q) error: %v", tt.input, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("parseDownloadWindows(%q) = %v, want %v", tt.input, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("parseDownloadWindows(%q) = %v, want %v", tt.input, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestDownloadWindowContains(t *testing.T) {
+	day := downloadWindow{start: 9 * 60, end: 17 * 60}
+	night := downloadWindow{start: 23 * 60, end: 7 * 60}
+	tests := []struct {
+		window  downloadWindow
+		minutes int
+		want    bool
+	}{
+		{day, 9 * 60, true},
+		{day, 12 * 60, true},
+		{day, 17 * 60, false},
+		{day, 8*60 + 59, false},
+		{night, 23 * 60, true},
+		{night, 23*60 + 59, true},
+		{night, 0, true},
+		{night, 6*60 + 59, true},
+		{night, 7 * 60, false},
+		{night, 12 * 60, false},
+		{night, 22*60 + 59, false},
+	}
+	for _, tt := range tests {
+		if got := tt.window.contains(tt.minutes); got != tt.want {
+			t.Errorf("%v.contains(%d) = %v, want %v", tt.window, tt.minutes, got, tt.want)
+		}
+	}
+}
+
+func TestNextDownloadWindowStart(t *testing.T) {
+	windows := []downloadWindow{{start: 23 * 60, end: 7 * 60}, {start: 12 * 60, end: 13 * 60}}
+	tests := []struct {
+		now  time.Time
+		want time.Time
+	}{
+		{time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC), time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+		{time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC), time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC)},
+		{time.Date(2024, 5, 1, 23, 30, 0, 0, time.UTC), time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := nextDownloadWindowStart(windows, tt.now); !got.Equal(tt.want) {
+			t.Errorf("nextDownloadWindowStart(%v) = %v, want %v", tt.now, got, tt.want)
+		}
+	}
+}
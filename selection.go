@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 视频ID格式，如 BV1xx411c7mD 或分P形式 BV1xx411c7mD_p2
+var itemIDRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*(_p\d+)?$`)
+
+// 序号范围，End 为 0 表示到最后一项
+type itemRange struct {
+	Start int
+	End   int
+}
+
+// 条目选择：序号、序号范围或视频ID，满足任一条件的条目都会被下载
+type ItemSelection struct {
+	Ranges []itemRange
+	IDs    []string
+}
+
+// 解析条目选择，如 "1,3,40-80,100-,BV1xx411c7mD"
+func parseItemSelection(s string) (ItemSelection, error) {
+	var sel ItemSelection
+
+	tokens := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '，' || r == ';' || r == ' ' || r == '\t' || r == '\n'
+	})
+	for _, token := range tokens {
+		if start, end, ok := strings.Cut(token, "-"); ok {
+			from, err := strconv.Atoi(start)
+			if err != nil || from < 1 {
+				return ItemSelection{}, fmt.Errorf("无效的序号范围: %s", token)
+			}
+			to := 0
+			if end != "" {
+				if to, err = strconv.Atoi(end); err != nil || to < from {
+					return ItemSelection{}, fmt.Errorf("无效的序号范围: %s", token)
+				}
+			}
+			sel.Ranges = append(sel.Ranges, itemRange{Start: from, End: to})
+			continue
+		}
+
+		if index, err := strconv.Atoi(token); err == nil {
+			if index < 1 {
+				return ItemSelection{}, fmt.Errorf("无效的序号: %s", token)
+			}
+			sel.Ranges = append(sel.Ranges, itemRange{Start: index, End: index})
+			continue
+		}
+
+		if !itemIDRegex.MatchString(token) {
+			return ItemSelection{}, fmt.Errorf("无效的条目: %s", token)
+		}
+		sel.IDs = append(sel.IDs, token)
+	}
+
+	return sel, nil
+}
+
+// 是否未选择任何条目（即下载全部）
+func (s ItemSelection) IsEmpty() bool {
+	return len(s.Ranges) == 0 && len(s.IDs) == 0
+}
+
+// 判断序号是否在选择范围内，只按视频ID选择时无法确定，返回 false
+func (s ItemSelection) ContainsIndex(index int) bool {
+	return slices.ContainsFunc(s.Ranges, func(r itemRange) bool {
+		return index >= r.Start && (r.End == 0 || index <= r.End)
+	})
+}
+
+// 判断条目是否被选中，不带分P的BV号匹配该视频的所有分P
+func (s ItemSelection) Contains(index int, id string) bool {
+	if s.ContainsIndex(index) {
+		return true
+	}
+	return id != "" && slices.ContainsFunc(s.IDs, func(selected string) bool {
+		return id == selected || (!strings.Contains(selected, "_p") && strings.HasPrefix(id, selected+"_p"))
+	})
+}
+
+// 转换为 --playlist-items 参数，包含视频ID时返回空字符串
+func (s ItemSelection) PlaylistItems() string {
+	if len(s.IDs) > 0 || len(s.Ranges) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, len(s.Ranges))
+	for _, r := range s.Ranges {
+		switch {
+		case r.Start == r.End:
+			parts = append(parts, strconv.Itoa(r.Start))
+		case r.End == 0:
+			parts = append(parts, fmt.Sprintf("%d:", r.Start))
+		default:
+			parts = append(parts, fmt.Sprintf("%d:%d", r.Start, r.End))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// 转换为 --match-filters 参数，多个过滤条件之间为“或”关系
+// 不带分P的BV号匹配该视频的所有分P
+func (s ItemSelection) MatchFilters() []string {
+	if len(s.IDs) == 0 {
+		return nil
+	}
+
+	filters := make([]string, 0, len(s.Ranges)+len(s.IDs))
+	for _, r := range s.Ranges {
+		switch {
+		case r.Start == r.End:
+			filters = append(filters, fmt.Sprintf("playlist_index=%d", r.Start))
+		case r.End == 0:
+			filters = append(filters, fmt.Sprintf("playlist_index>=%d", r.Start))
+		default:
+			filters = append(filters, fmt.Sprintf("playlist_index>=%d & playlist_index<=%d", r.Start, r.End))
+		}
+	}
+	for _, id := range s.IDs {
+		if strings.Contains(id, "_p") {
+			filters = append(filters, fmt.Sprintf("id=%s", id))
+		} else {
+			filters = append(filters, fmt.Sprintf("id^=%s", id))
+		}
+	}
+	return filters
+}
+
+// 条目筛选：按上传日期范围和标题关键字筛选，主要用于UP主全部投稿
+type CatalogFilter struct {
+	TitleKeyword string
+	DateAfter    string // 上传日期下限（含），格式 YYYYMMDD
+	DateBefore   string // 上传日期上限（含），格式 YYYYMMDD
+}
+
+// 解析条目筛选条件，日期格式为 YYYY-MM-DD
+func parseCatalogFilter(keyword, dateAfter, dateBefore string) (CatalogFilter, error) {
+	filter := CatalogFilter{TitleKeyword: strings.TrimSpace(keyword)}
+
+	for _, d := range []struct {
+		value  string
+		target *string
+	}{{dateAfter, &filter.DateAfter}, {dateBefore, &filter.DateBefore}} {
+		if d.value == "" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", d.value)
+		if err != nil {
+			return CatalogFilter{}, fmt.Errorf("无效的日期: %s，应为 YYYY-MM-DD 格式", d.value)
+		}
+		*d.target = date.Format("20060102")
+	}
+
+	if filter.DateAfter != "" && filter.DateBefore != "" && filter.DateAfter > filter.DateBefore {
+		return CatalogFilter{}, fmt.Errorf("开始日期不能晚于结束日期")
+	}
+	return filter, nil
+}
+
+// 是否未设置任何筛选条件
+func (f CatalogFilter) IsEmpty() bool {
+	return f.TitleKeyword == "" && f.DateAfter == "" && f.DateBefore == ""
+}
+
+// 判断标题是否包含关键字（不区分大小写），标题未知时视为符合
+func (f CatalogFilter) MatchesTitle(title string) bool {
+	return f.TitleKeyword == "" || title == "" ||
+		strings.Contains(strings.ToLower(title), strings.ToLower(f.TitleKeyword))
+}
+
+// 判断上传日期（YYYYMMDD）是否在范围内，日期未知时视为符合
+func (f CatalogFilter) MatchesDate(uploadDate string) bool {
+	if uploadDate == "" {
+		return true
+	}
+	return (f.DateAfter == "" || uploadDate >= f.DateAfter) &&
+		(f.DateBefore == "" || uploadDate <= f.DateBefore)
+}
+
+// 转换为 yt-dlp 参数，与条目选择的过滤条件合并：
+// 多个 --match-filters 之间为“或”关系，因此关键字条件需要加到每个选择条件上
+func (f CatalogFilter) YtDlpArgs(selectionFilters []string) []string {
+	var args []string
+
+	filters := selectionFilters
+	if f.TitleKeyword != "" {
+		// yt-dlp 按未转义的 & 拆分过滤条件（引号内也会拆分），因此 & 需要转义
+		keyword := strings.NewReplacer("'", `\'`, "&", `\&`).Replace(regexp.QuoteMeta(f.TitleKeyword))
+		keywordFilter := fmt.Sprintf("title~='(?i)%s'", keyword)
+		if len(filters) == 0 {
+			filters = []string{keywordFilter}
+		} else {
+			combined := make([]string, 0, len(filters))
+			for _, filter := range filters {
+				combined = append(combined, filter+" & "+keywordFilter)
+			}
+			filters = combined
+		}
+	}
+	for _, filter := range filters {
+		args = append(args, "--match-filters", filter)
+	}
+
+	if f.DateAfter != "" {
+		args = append(args, "--dateafter", f.DateAfter)
+	}
+	if f.DateBefore != "" {
+		args = append(args, "--datebefore", f.DateBefore)
+	}
+	return args
+}
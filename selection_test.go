@@ -0,0 +1,101 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseItemSelection(t *testing.T) {
+	tests := []struct {
+		input         string
+		playlistItems string
+		matchFilters  []string
+		wantErr       bool
+	}{
+		{input: "", playlistItems: ""},
+		{input: "1,3,40-80,100-", playlistItems: "1,3,40:80,100:"},
+		{input: "1，2;3", playlistItems: "1,2,3"},
+		{input: "2 5\t7", playlistItems: "2,5,7"},
+		{input: "1,BV1xx411c7mD", matchFilters: []string{"playlist_index=1", "id^=BV1xx411c7mD"}},
+		{input: "10-,BV1xx411c7mD_p2", matchFilters: []string{"playlist_index>=10", "id=BV1xx411c7mD_p2"}},
+		{input: "3-5,BV1aa", matchFilters: []string{"playlist_index>=3 & playlist_index<=5", "id^=BV1aa"}},
+		{input: "0", wantErr: true},
+		{input: "5-3", wantErr: true},
+		{input: "-3", wantErr: true},
+		{input: "a-b", wantErr: true},
+		{input: "BV1xx!", wantErr: true},
+	}
+	for _, tt := range tests {
+		sel, err := parseItemSelection(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseItemSelection(%q) = %+v, want error", tt.input, sel)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseItemSelection(%q) error: %v", tt.input, err)
+			continue
+		}
+		if got := sel.PlaylistItems(); got != tt.playlistItems {
+			t.Errorf("parseItemSelection(%q).PlaylistItems() = %q, want %q", tt.input, got, tt.playlistItems)
+		}
+		if got := sel.MatchFilters(); !slices.Equal(got, tt.matchFilters) {
+			t.Errorf("parseItemSelection(%q).MatchFilters() = %q, want %q", tt.input, got, tt.matchFilters)
+		}
+	}
+}
+
+func TestItemSelectionContains(t *testing.T) {
+	sel, err := parseItemSelection("2,10-,BV1aa,BV1bb_p3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		index int
+		id    string
+		want  bool
+	}{
+		{2, "", true},
+		{3, "", false},
+		{12, "", true},
+		{1, "BV1aa", true},
+		{1, "BV1aa_p4", true},
+		{1, "BV1bb_p3", true},
+		{1, "BV1bb_p1", false},
+		{1, "BV1bb", false},
+	}
+	for _, tt := range tests {
+		if got := sel.Contains(tt.index, tt.id); got != tt.want {
+			t.Errorf("Contains(%d, %q) = %v, want %v", tt.index, tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestCatalogFilterYtDlpArgs(t *testing.T) {
+	tests := []struct {
+		filter    CatalogFilter
+		selection []string
+		want      []string
+	}{
+		{CatalogFilter{}, nil, nil},
+		{CatalogFilter{TitleKeyword: "第一季"}, nil, []string{"--match-filters", "title~='(?i)第一季'"}},
+		{CatalogFilter{TitleKeyword: "A&B"}, nil, []string{"--match-filters", `title~='(?i)A\&B'`}},
+		{CatalogFilter{TitleKeyword: "it's 1.5"}, nil, []string{"--match-filters", `title~='(?i)it\'s 1\.5'`}},
+		{
+			CatalogFilter{TitleKeyword: "正片", DateAfter: "20240101", DateBefore: "20241231"},
+			[]string{"playlist_index=1", "id^=BV1aa"},
+			[]string{
+				"--match-filters", "playlist_index=1 & title~='(?i)正片'",
+				"--match-filters", "id^=BV1aa & title~='(?i)正片'",
+				"--dateafter", "20240101",
+				"--datebefore", "20241231",
+			},
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.YtDlpArgs(tt.selection); !slices.Equal(got, tt.want) {
+			t.Errorf("%+v.YtDlpArgs(%q) = %q, want %q", tt.filter, tt.selection, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,704 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 订阅默认检查间隔（分钟）
+const defaultSubscriptionIntervalMinutes = 60
+
+// 订阅最短检查间隔（分钟），避免频繁请求
+const minSubscriptionIntervalMinutes = 10
+
+// 订阅调度器检查到期订阅的间隔
+const subscriptionTickInterval = time.Minute
+
+// 每个订阅下载任务最多包含的条目数，每个条目占用一个 --match-filters 参数，避免命令行超出长度限制
+const subscriptionTaskMaxEntries = 50
+
+// 订阅：定期检查合集、系列、UP主投稿或收藏夹，自动下载新增的条目
+type Subscription struct {
+	ID               int                `json:"id"`
+	Title            string             `json:"title,omitempty"`
+	URL              string             `json:"url"`
+	Request          DownloadRequest    `json:"request"` // 下载选项，url 与订阅链接相同
+	Filter           SubscriptionFilter `json:"filter"`
+	IntervalMinutes  int                `json:"interval_minutes"`
+	Enabled          bool               `json:"enabled"`
+	CreatedAt        string             `json:"created_at"`
+	LastCheckedAt    string             `json:"last_checked_at,omitempty"`
+	LastError        string             `json:"last_error,omitempty"`
+	LastQueuedCount  int                `json:"last_queued_count"`            // 最近一次检查加入队列的条目数
+	LastSkippedCount int                `json:"last_skipped_count,omitempty"` // 最近一次检查被筛选条件排除的条目数
+	LastTaskID       string             `json:"last_task_id,omitempty"`       // 最近一次创建的下载任务
+	KnownIDs         []string           `json:"known_ids,omitempty"`          // 已下载完成（或文件已存在而跳过）的条目ID
+	SkippedIDs       []string           `json:"skipped_ids,omitempty"`        // 不符合筛选条件的条目ID，修改筛选条件后重新判断
+	checking         bool               // 正在检查，避免同一订阅被重复检查
+}
+
+// 创建或更新订阅的请求
+type SubscriptionRequest struct {
+	DownloadRequest
+	Filter          SubscriptionFilter `json:"filter"`
+	IntervalMinutes int                `json:"interval_minutes,omitempty"`
+	Enabled         *bool              `json:"enabled,omitempty"` // 不传时默认启用
+}
+
+// 订阅的条目筛选条件，按预检查得到的条目信息判断，信息未知时视为符合
+type SubscriptionFilter struct {
+	IncludeRegex       string `json:"include_regex,omitempty"`        // 标题需匹配的正则
+	ExcludeRegex       string `json:"exclude_regex,omitempty"`        // 标题匹配时排除的正则
+	MinDurationMinutes int    `json:"min_duration_minutes,omitempty"` // 最短时长（分钟）
+	MaxDurationMinutes int    `json:"max_duration_minutes,omitempty"` // 最长时长（分钟）
+	UploadedAfter      string `json:"uploaded_after,omitempty"`       // 只保留此日期（YYYY-MM-DD，含当天）之后上传的条目
+}
+
+// 编译后的筛选条件
+type subscriptionMatcher struct {
+	include       *regexp.Regexp
+	exclude       *regexp.Regexp
+	minDuration   float64 // 秒
+	maxDuration   float64 // 秒
+	uploadedAfter string  // YYYYMMDD
+}
+
+// 是否没有设置任何筛选条件
+func (f SubscriptionFilter) IsEmpty() bool {
+	return f == SubscriptionFilter{}
+}
+
+// 校验并编译筛选条件
+func (f SubscriptionFilter) compile() (*subscriptionMatcher, error) {
+	if f.MinDurationMinutes < 0 || f.MaxDurationMinutes < 0 {
+		return nil, fmt.Errorf("时长不能为负数")
+	}
+	if f.MaxDurationMinutes > 0 && f.MinDurationMinutes > f.MaxDurationMinutes {
+		return nil, fmt.Errorf("最短时长不能大于最长时长")
+	}
+	m := &subscriptionMatcher{
+		minDuration: float64(f.MinDurationMinutes * 60),
+		maxDuration: float64(f.MaxDurationMinutes * 60),
+	}
+
+	var err error
+	if f.IncludeRegex != "" {
+		if m.include, err = regexp.Compile(f.IncludeRegex); err != nil {
+			return nil, fmt.Errorf("无效的包含正则: %v", err)
+		}
+	}
+	if f.ExcludeRegex != "" {
+		if m.exclude, err = regexp.Compile(f.ExcludeRegex); err != nil {
+			return nil, fmt.Errorf("无效的排除正则: %v", err)
+		}
+	}
+
+	if f.UploadedAfter != "" {
+		date, err := time.Parse("2006-01-02", f.UploadedAfter)
+		if err != nil {
+			return nil, fmt.Errorf("无效的日期: %s，应为 YYYY-MM-DD 格式", f.UploadedAfter)
+		}
+		m.uploadedAfter = date.Format("20060102")
+	}
+	return m, nil
+}
+
+// 条目筛选结果
+const (
+	filterMatched   = iota // 符合筛选条件
+	filterRejected         // 不符合筛选条件
+	filterUndecided        // 缺少筛选需要的信息，下次检查时重新判断
+)
+
+// 判断条目是否符合筛选条件，不符合或无法判断时返回原因
+func (m *subscriptionMatcher) Match(video VideoInfo) (int, string) {
+	// 先确认筛选需要的信息都已获取，缺少信息时不做判断
+	title := video.RealTitle()
+	if (m.include != nil || m.exclude != nil) && title == "" {
+		return filterUndecided, "缺少标题信息"
+	}
+	if (m.minDuration > 0 || m.maxDuration > 0) && video.DurationSeconds <= 0 {
+		return filterUndecided, "缺少时长信息"
+	}
+	if m.uploadedAfter != "" && video.UploadDate == "" {
+		return filterUndecided, "缺少上传日期信息"
+	}
+
+	if m.include != nil && !m.include.MatchString(title) {
+		return filterRejected, "标题不匹配包含规则"
+	}
+	if m.exclude != nil && m.exclude.MatchString(title) {
+		return filterRejected, "标题匹配排除规则"
+	}
+	if m.minDuration > 0 && video.DurationSeconds < m.minDuration {
+		return filterRejected, fmt.Sprintf("时长 %s 短于最短时长", video.Duration)
+	}
+	if m.maxDuration > 0 && video.DurationSeconds > m.maxDuration {
+		return filterRejected, fmt.Sprintf("时长 %s 超过最长时长", video.Duration)
+	}
+	if m.uploadedAfter != "" && video.UploadDate < m.uploadedAfter {
+		return filterRejected, "上传日期早于 " + m.uploadedAfter
+	}
+	return filterMatched, ""
+}
+
+// 订阅预览中的条目
+type SubscriptionPreviewEntry struct {
+	VideoInfo
+	Matched    bool   `json:"matched"`
+	Undecided  bool   `json:"undecided,omitempty"`  // 缺少筛选需要的信息，订阅检查时会重新判断
+	Reason     string `json:"reason,omitempty"`     // 不符合筛选条件的原因
+	Downloaded bool   `json:"downloaded,omitempty"` // 已在下载存档中
+}
+
+// 订阅预览结果
+type SubscriptionPreview struct {
+	Title        string                     `json:"title"`
+	Uploader     string                     `json:"uploader,omitempty"`
+	Entries      []SubscriptionPreviewEntry `json:"entries"`
+	MatchedCount int                        `json:"matched_count"` // 符合筛选条件且尚未下载的条目数
+}
+
+// 订阅存储
+var (
+	subscriptionMutex  sync.Mutex
+	subscriptionList   []*Subscription
+	nextSubscriptionID int = 1
+)
+
+// 获取订阅文件路径
+func getSubscriptionsPath() string {
+	return filepath.Join("config", "subscriptions.json")
+}
+
+// 从磁盘加载订阅并启动定时检查
+func initializeSubscriptions() {
+	subscriptionMutex.Lock()
+	defer subscriptionMutex.Unlock()
+
+	data, err := os.ReadFile(getSubscriptionsPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("读取订阅失败: %v\n", err)
+		}
+	} else if err := json.Unmarshal(data, &subscriptionList); err != nil {
+		fmt.Printf("解析订阅失败: %v\n", err)
+		subscriptionList = nil
+	}
+
+	for _, sub := range subscriptionList {
+		if sub.ID >= nextSubscriptionID {
+			nextSubscriptionID = sub.ID + 1
+		}
+	}
+	fmt.Printf("订阅初始化完成，共加载 %d 个订阅\n", len(subscriptionList))
+
+	go runSubscriptionScheduler()
+}
+
+// 将订阅写入磁盘，调用方需持有 subscriptionMutex
+func saveSubscriptionsLocked() error {
+	return writeJSONFile(getSubscriptionsPath(), subscriptionList)
+}
+
+// 校验订阅请求，返回规范化后的链接
+func validateSubscriptionRequest(req *SubscriptionRequest) (string, error) {
+	parsedURL, err := parseURL(req.URL)
+	if err != nil {
+		return "", fmt.Errorf("链接解析失败: %v", err)
+	}
+	if _, ok := parseLiveRoomURL(parsedURL); ok {
+		return "", fmt.Errorf("直播间不支持订阅")
+	}
+	if strings.Contains(parsedURL, "/video/BV") {
+		return "", fmt.Errorf("单个视频不支持订阅，请使用合集、系列、UP主投稿或收藏夹链接")
+	}
+	if req.Items != "" {
+		return "", fmt.Errorf("订阅会自动选择新增条目，不支持指定 items")
+	}
+	if _, err := req.Filter.compile(); err != nil {
+		return "", fmt.Errorf("筛选条件无效: %v", err)
+	}
+
+	// 用下载任务的校验规则检查其余选项
+	if _, err := newDownloadTask(req.DownloadRequest); err != nil {
+		return "", err
+	}
+
+	if req.IntervalMinutes == 0 {
+		req.IntervalMinutes = defaultSubscriptionIntervalMinutes
+	}
+	if req.IntervalMinutes < minSubscriptionIntervalMinutes {
+		return "", fmt.Errorf("检查间隔不能小于 %d 分钟", minSubscriptionIntervalMinutes)
+	}
+	return parsedURL, nil
+}
+
+// 订阅快照，避免调用方访问共享数据
+func cloneSubscription(sub *Subscription) Subscription {
+	clone := *sub
+	clone.KnownIDs = slices.Clone(sub.KnownIDs)
+	clone.SkippedIDs = slices.Clone(sub.SkippedIDs)
+	return clone
+}
+
+// 获取所有订阅
+func listSubscriptions() []Subscription {
+	subscriptionMutex.Lock()
+	defer subscriptionMutex.Unlock()
+
+	list := make([]Subscription, 0, len(subscriptionList))
+	for _, sub := range subscriptionList {
+		list = append(list, cloneSubscription(sub))
+	}
+	return list
+}
+
+// 按ID查找订阅，调用方需持有 subscriptionMutex
+func findSubscriptionLocked(id int) *Subscription {
+	for _, sub := range subscriptionList {
+		if sub.ID == id {
+			return sub
+		}
+	}
+	return nil
+}
+
+// 获取单个订阅
+func getSubscription(id int) (Subscription, bool) {
+	subscriptionMutex.Lock()
+	defer subscriptionMutex.Unlock()
+
+	sub := findSubscriptionLocked(id)
+	if sub == nil {
+		return Subscription{}, false
+	}
+	return cloneSubscription(sub), true
+}
+
+// 创建订阅，创建后立即检查一次
+func createSubscription(req SubscriptionRequest) (Subscription, error) {
+	parsedURL, err := validateSubscriptionRequest(&req)
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	subscriptionMutex.Lock()
+	sub := &Subscription{
+		ID:              nextSubscriptionID,
+		URL:             parsedURL,
+		Request:         req.DownloadRequest,
+		Filter:          req.Filter,
+		IntervalMinutes: req.IntervalMinutes,
+		Enabled:         req.Enabled == nil || *req.Enabled,
+		CreatedAt:       time.Now().Format("2006-01-02 15:04:05"),
+	}
+	nextSubscriptionID++
+	subscriptionList = append(subscriptionList, sub)
+	if err := saveSubscriptionsLocked(); err != nil {
+		fmt.Printf("保存订阅失败: %v\n", err)
+	}
+	snapshot := cloneSubscription(sub)
+	subscriptionMutex.Unlock()
+
+	if snapshot.Enabled {
+		go checkSubscription(snapshot.ID)
+	}
+	return snapshot, nil
+}
+
+// 更新订阅的链接和下载选项，已记录的条目保持不变
+func updateSubscription(id int, req SubscriptionRequest) (Subscription, error) {
+	parsedURL, err := validateSubscriptionRequest(&req)
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	subscriptionMutex.Lock()
+	defer subscriptionMutex.Unlock()
+
+	sub := findSubscriptionLocked(id)
+	if sub == nil {
+		return Subscription{}, fmt.Errorf("订阅不存在")
+	}
+	if sub.URL != parsedURL {
+		sub.Title = ""
+	}
+	sub.URL = parsedURL
+	if sub.Filter != req.Filter {
+		sub.SkippedIDs = nil
+	}
+	sub.Request = req.DownloadRequest
+	sub.Filter = req.Filter
+	sub.IntervalMinutes = req.IntervalMinutes
+	if req.Enabled != nil {
+		sub.Enabled = *req.Enabled
+	}
+	if err := saveSubscriptionsLocked(); err != nil {
+		fmt.Printf("保存订阅失败: %v\n", err)
+	}
+	return cloneSubscription(sub), nil
+}
+
+// 删除订阅，已创建的下载任务不受影响
+func deleteSubscription(id int) bool {
+	subscriptionMutex.Lock()
+	defer subscriptionMutex.Unlock()
+
+	before := len(subscriptionList)
+	subscriptionList = slices.DeleteFunc(subscriptionList, func(sub *Subscription) bool {
+		return sub.ID == id
+	})
+	if len(subscriptionList) == before {
+		return false
+	}
+	if err := saveSubscriptionsLocked(); err != nil {
+		fmt.Printf("保存订阅失败: %v\n", err)
+	}
+	return true
+}
+
+// 定时检查到期的订阅
+func runSubscriptionScheduler() {
+	ticker := time.NewTicker(subscriptionTickInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for _, id := range dueSubscriptionIDs(time.Now()) {
+			checkSubscription(id)
+		}
+	}
+}
+
+// 获取需要检查的订阅
+func dueSubscriptionIDs(now time.Time) []int {
+	subscriptionMutex.Lock()
+	defer subscriptionMutex.Unlock()
+
+	var ids []int
+	for _, sub := range subscriptionList {
+		if !sub.Enabled || sub.checking {
+			continue
+		}
+		lastChecked, err := time.ParseInLocation("2006-01-02 15:04:05", sub.LastCheckedAt, time.Local)
+		if err != nil || !now.Before(lastChecked.Add(time.Duration(sub.IntervalMinutes)*time.Minute)) {
+			ids = append(ids, sub.ID)
+		}
+	}
+	return ids
+}
+
+// 检查订阅，将未下载过的新条目加入下载队列，返回加入的条目数
+func checkSubscription(id int) (int, error) {
+	subscriptionMutex.Lock()
+	sub := findSubscriptionLocked(id)
+	if sub == nil {
+		subscriptionMutex.Unlock()
+		return 0, fmt.Errorf("订阅不存在")
+	}
+	if sub.checking {
+		subscriptionMutex.Unlock()
+		return 0, fmt.Errorf("订阅正在检查中")
+	}
+	sub.checking = true
+	snapshot := cloneSubscription(sub)
+	subscriptionMutex.Unlock()
+
+	// 订阅任务中已完成的条目记为已下载，仍在队列中的条目本次不再加入
+	finishedIDs, pendingIDs := subscriptionTaskIDs(snapshot.ID)
+	snapshot.KnownIDs = append(snapshot.KnownIDs, finishedIDs...)
+
+	fmt.Printf("检查订阅 %d: %s\n", snapshot.ID, snapshot.URL)
+	title, newIDs, skippedIDs, err := findNewSubscriptionEntries(snapshot, pendingIDs)
+
+	var taskIDs []string
+	if err == nil && len(newIDs) > 0 {
+		taskIDs, err = queueSubscriptionEntries(snapshot, newIDs)
+	}
+
+	subscriptionMutex.Lock()
+	defer subscriptionMutex.Unlock()
+
+	sub.checking = false
+	addKnownIDsLocked(sub, finishedIDs)
+	defer func() {
+		if saveErr := saveSubscriptionsLocked(); saveErr != nil {
+			fmt.Printf("保存订阅失败: %v\n", saveErr)
+		}
+	}()
+	sub.LastCheckedAt = time.Now().Format("2006-01-02 15:04:05")
+	sub.LastError = ""
+	sub.LastQueuedCount = 0
+	sub.LastSkippedCount = 0
+	if title != "" {
+		sub.Title = title
+	}
+	if err != nil {
+		fmt.Printf("检查订阅 %d 失败: %v\n", snapshot.ID, err)
+		sub.LastError = err.Error()
+		return 0, err
+	}
+	if len(skippedIDs) > 0 {
+		sub.LastSkippedCount = len(skippedIDs)
+		sub.SkippedIDs = append(sub.SkippedIDs, skippedIDs...)
+	}
+	if len(newIDs) > 0 {
+		fmt.Printf("订阅 %d 发现 %d 个新条目，已创建下载任务 %s\n", snapshot.ID, len(newIDs), strings.Join(taskIDs, ", "))
+		sub.LastQueuedCount = len(newIDs)
+		sub.LastTaskID = taskIDs[len(taskIDs)-1]
+	}
+	return len(newIDs), nil
+}
+
+// 记录已下载完成的条目ID，调用方需持有 subscriptionMutex
+func addKnownIDsLocked(sub *Subscription, ids []string) {
+	for _, id := range ids {
+		if !slices.Contains(sub.KnownIDs, id) {
+			sub.KnownIDs = append(sub.KnownIDs, id)
+		}
+	}
+}
+
+// 汇总订阅创建的下载任务中已完成的条目ID和仍在队列中未完成的条目ID
+func subscriptionTaskIDs(subscriptionID int) ([]string, []string) {
+	downloadMutex.RLock()
+	defer downloadMutex.RUnlock()
+
+	var finished, pending []string
+	for _, task := range downloadTasks {
+		if task.subscriptionID != subscriptionID {
+			continue
+		}
+		taskFinished, taskPending := subscriptionTaskEntriesLocked(task)
+		finished = append(finished, taskFinished...)
+		pending = append(pending, taskPending...)
+	}
+	return finished, pending
+}
+
+// 任务选中的条目中已下载完成或已存在而跳过的条目ID，以及任务尚未结束时其余的条目ID
+// 多P视频的所有分P都完成后才算完成，调用方需持有 downloadMutex
+func subscriptionTaskEntriesLocked(task *DownloadTask) ([]string, []string) {
+	done := make(map[string]bool)
+	for _, item := range task.Items {
+		if item.ID == "" {
+			continue
+		}
+		videoID, _, _ := strings.Cut(item.ID, "_p")
+		itemDone := item.Status == ItemStateDone || item.Status == ItemStateSkipped
+		if previous, ok := done[videoID]; ok {
+			itemDone = itemDone && previous
+		}
+		done[videoID] = itemDone
+	}
+
+	active := task.runActive || task.State == TaskStateQueued || task.State == TaskStateRunning || task.State == TaskStatePaused
+	var finished, pending []string
+	for _, id := range task.Selection.IDs {
+		if done[id] {
+			finished = append(finished, id)
+		} else if active {
+			pending = append(pending, id)
+		}
+	}
+	return finished, pending
+}
+
+// 删除订阅创建的任务前记录其中已完成的条目，避免之后重复下载
+func recordSubscriptionTask(task *DownloadTask) {
+	downloadMutex.RLock()
+	subscriptionID := task.subscriptionID
+	finished, _ := subscriptionTaskEntriesLocked(task)
+	downloadMutex.RUnlock()
+	if subscriptionID == 0 || len(finished) == 0 {
+		return
+	}
+
+	subscriptionMutex.Lock()
+	defer subscriptionMutex.Unlock()
+	sub := findSubscriptionLocked(subscriptionID)
+	if sub == nil {
+		return
+	}
+	addKnownIDsLocked(sub, finished)
+	if err := saveSubscriptionsLocked(); err != nil {
+		fmt.Printf("保存订阅失败: %v\n", err)
+	}
+}
+
+// 获取已下载或已处理过的条目ID
+func subscriptionDownloadedIDs(req DownloadRequest, processed ...[]string) (map[string]bool, error) {
+	downloaded := make(map[string]bool)
+	for _, ids := range processed {
+		for _, id := range ids {
+			downloaded[id] = true
+		}
+	}
+
+	// 下载存档中的视频ID可能带有分P后缀（如 BV1xx411c7mD_p2）
+	if isDownloadArchiveEnabled() && !req.IgnoreArchive {
+		archived, err := loadDownloadArchive(req.SavePath)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range archived {
+			videoID, _, _ := strings.Cut(id, "_p")
+			downloaded[videoID] = true
+		}
+	}
+	return downloaded, nil
+}
+
+// 列出订阅链接的全部条目，找出尚未下载且不在队列中的条目ID，并按筛选条件分为需要下载和排除的条目
+func findNewSubscriptionEntries(sub Subscription, pendingIDs []string) (string, []string, []string, error) {
+	title, entryIDs, err := listPlaylistEntryIDs(sub.URL)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	downloaded, err := subscriptionDownloadedIDs(sub.Request, sub.KnownIDs, sub.SkippedIDs, pendingIDs)
+	if err != nil {
+		return title, nil, nil, err
+	}
+
+	var newIDs []string
+	for _, id := range entryIDs {
+		if !downloaded[id] {
+			newIDs = append(newIDs, id)
+		}
+	}
+	if len(newIDs) == 0 || sub.Filter.IsEmpty() {
+		return title, newIDs, nil, nil
+	}
+
+	// 获取新条目的时长、上传日期等信息后按筛选条件判断
+	// 超出补全数量或补全超时、缺少信息的条目暂不处理，下次检查时重新判断
+	matcher, err := sub.Filter.compile()
+	if err != nil {
+		return title, nil, nil, err
+	}
+	selection, err := parseItemSelection(strings.Join(newIDs, ","))
+	if err != nil {
+		return title, nil, nil, err
+	}
+	info, err := getVideoInfo(sub.URL, resolveQuality(sub.Request.Quality), selection, CatalogFilter{})
+	if err != nil {
+		return title, nil, nil, err
+	}
+
+	var matched, skipped []string
+	for _, entry := range info.Entries {
+		if !slices.Contains(newIDs, entry.ID) {
+			continue
+		}
+		switch result, reason := matcher.Match(entry); result {
+		case filterMatched:
+			matched = append(matched, entry.ID)
+		case filterRejected:
+			fmt.Printf("订阅 %d 跳过条目 %s（%s）: %s\n", sub.ID, entry.ID, entry.Title, reason)
+			skipped = append(skipped, entry.ID)
+		}
+	}
+	return title, matched, skipped, nil
+}
+
+// 预览订阅链接的条目，标记符合筛选条件和已下载的条目
+func previewSubscription(req SubscriptionRequest) (*SubscriptionPreview, error) {
+	parsedURL, err := validateSubscriptionRequest(&req)
+	if err != nil {
+		return nil, err
+	}
+	matcher, _ := req.Filter.compile()
+	filter, _ := parseCatalogFilter(req.TitleKeyword, req.DateAfter, req.DateBefore)
+
+	info, err := getVideoInfo(parsedURL, resolveQuality(req.Quality), ItemSelection{}, filter)
+	if err != nil {
+		return nil, fmt.Errorf("获取视频信息失败: %v", err)
+	}
+	downloaded, err := subscriptionDownloadedIDs(req.DownloadRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &SubscriptionPreview{
+		Title:    info.Title,
+		Uploader: info.Uploader,
+		Entries:  make([]SubscriptionPreviewEntry, 0, len(info.Entries)),
+	}
+	for _, entry := range info.Entries {
+		item := SubscriptionPreviewEntry{VideoInfo: entry, Downloaded: downloaded[entry.ID]}
+		result, reason := matcher.Match(entry)
+		item.Matched = result == filterMatched
+		item.Undecided = result == filterUndecided
+		item.Reason = reason
+		if item.Matched && (!filter.MatchesTitle(entry.RealTitle()) || !filter.MatchesDate(entry.UploadDate)) {
+			item.Matched, item.Reason = false, "不符合标题关键字或上传日期范围"
+		}
+		if item.Matched && !item.Downloaded {
+			preview.MatchedCount++
+		}
+		preview.Entries = append(preview.Entries, item)
+	}
+	return preview, nil
+}
+
+// 使用 yt-dlp 平铺列出播放列表的标题和条目ID
+func listPlaylistEntryIDs(url string) (string, []string, error) {
+	args := []string{"--dump-single-json", "--no-download", "--flat-playlist", "--no-warnings"}
+	if hasCookies() {
+		args = append(args, "--cookies", getCookiesPath())
+	}
+	args = append(args, ytDlpProxyArgs()...)
+	args = append(args, url)
+
+	output, err := exec.Command(getYtDlpPath(), args...).Output()
+	if err != nil {
+		return "", nil, fmt.Errorf("执行yt-dlp失败: %v", err)
+	}
+
+	var info map[string]interface{}
+	if err := json.Unmarshal(output, &info); err != nil {
+		return "", nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+
+	rawEntries, ok := info["entries"].([]interface{})
+	if !ok {
+		return "", nil, fmt.Errorf("链接不是播放列表")
+	}
+	ids := make([]string, 0, len(rawEntries))
+	for _, raw := range rawEntries {
+		if entry, ok := raw.(map[string]interface{}); ok {
+			if id := getString(entry, "id"); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return getString(info, "title"), ids, nil
+}
+
+// 为新条目创建下载任务并加入队列，条目较多时分为多个任务，返回创建的任务ID
+func queueSubscriptionEntries(sub Subscription, entryIDs []string) ([]string, error) {
+	var taskIDs []string
+	for batch := range slices.Chunk(entryIDs, subscriptionTaskMaxEntries) {
+		req := sub.Request
+		req.URL = sub.URL
+		req.Items = strings.Join(batch, ",")
+
+		task, err := newDownloadTask(req)
+		if err != nil {
+			return taskIDs, err
+		}
+		task.subscriptionID = sub.ID
+		enqueueDownload(task)
+		taskIDs = append(taskIDs, task.ID)
+	}
+	return taskIDs, nil
+}